@@ -20,14 +20,23 @@ type AIClient struct {
 	lmstudioURL    string
 	lmstudioModel  string
 	lmstudioAPIKey string
+	aiGenerated    bool // Include generated/vendored files in risk analysis
 	httpClient     *http.Client
 }
 
 type AIRiskAssessment struct {
-	RiskScore     int      `json:"riskScore"`
-	Reasons       []string `json:"reasons"`
-	SemanticGroup string   `json:"semanticGroup"`
-	Confidence    string   `json:"confidence"`
+	RiskScore     int          `json:"riskScore"`
+	Reasons       []string     `json:"reasons"`
+	SemanticGroup string       `json:"semanticGroup"`
+	Confidence    string       `json:"confidence"`
+	Hunks         []AIHunkRisk `json:"hunks"`
+}
+
+// AIHunkRisk is the AI's score for one hunk of the assessed file.
+type AIHunkRisk struct {
+	Index     int    `json:"index"`
+	RiskScore int    `json:"riskScore"`
+	Reason    string `json:"reason"`
 }
 
 // NewAIClient creates an AIClient based on the configuration.
@@ -44,6 +53,7 @@ func NewAIClient(cfg *Config) *AIClient {
 		lmstudioURL:    cfg.LMStudioURL,
 		lmstudioModel:  cfg.LMStudioModel,
 		lmstudioAPIKey: cfg.LMStudioAPIKey,
+		aiGenerated:    cfg.AIGenerated,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -64,13 +74,14 @@ func (ai *AIClient) AssessRiskWithContext(ctx context.Context, file *DiffFile) (
 	prompt := fmt.Sprintf(`You are a staff engineer performing risk triage for a git diff.
 
 Return ONLY valid JSON with this exact shape:
-{"riskScore": number, "reasons": [string], "semanticGroup": "feature|bugfix|refactor|test|config|docs|style", "confidence": "low|medium|high"}
+{"riskScore": number, "reasons": [string], "semanticGroup": "feature|bugfix|refactor|test|config|docs|style", "confidence": "low|medium|high", "hunks": [{"index": number, "riskScore": number, "reason": string}]}
 
 Rules:
 - riskScore is 0-100 where 0 is trivial and 100 is very risky.
 - reasons must be 2-5 short, concrete reasons tied to THIS diff.
 - semanticGroup must be one of the listed values.
 - confidence should reflect certainty in your assessment.
+- hunks scores each "[hunk N]" shown in the diff on the same 0-100 scale, with one short reason.
 - Do not include markdown code fences or extra text.
 
 File: %s
@@ -83,7 +94,7 @@ Current heuristic reasons: %s
 Current heuristic semantic group: %s
 
 Diff:
-%s`, file.Path, file.Status, file.Language, file.LinesAdded, file.LinesRemoved, file.RiskScore, strings.Join(file.RiskReasons, ", "), file.SemanticGroup, truncate(file.RawDiff, 2200))
+%s`, file.Path, file.Status, file.Language, file.LinesAdded, file.LinesRemoved, file.RiskScore, strings.Join(file.RiskReasons, ", "), file.SemanticGroup, aiHunkedDiffText(file, 2200))
 
 	result, err := ai.complete(ctx, prompt)
 	if err != nil {
@@ -117,6 +128,11 @@ func (ai *AIClient) RiskConcurrency() int {
 	return 3
 }
 
+// IncludeGenerated reports whether generated and vendored files should be sent to the AI.
+func (ai *AIClient) IncludeGenerated() bool {
+	return ai != nil && ai.aiGenerated
+}
+
 // SummarizeFile generates a natural language summary for a file diff.
 func (ai *AIClient) SummarizeFile(file *DiffFile) (string, error) {
 	if ai == nil {
@@ -134,7 +150,7 @@ Lines removed: %d
 Diff:
 %s
 
-Respond with ONLY the summary, no preamble or formatting.`, file.Path, file.Status, file.Language, file.LinesAdded, file.LinesRemoved, truncate(file.RawDiff, 4000))
+Respond with ONLY the summary, no preamble or formatting.`, file.Path, file.Status, file.Language, file.LinesAdded, file.LinesRemoved, aiDiffText(file, 4000))
 
 	return ai.complete(context.Background(), prompt)
 }
@@ -145,6 +161,11 @@ func (ai *AIClient) SummarizeHunk(file *DiffFile, hunk *DiffHunk) (string, error
 		return "", fmt.Errorf("no AI provider configured")
 	}
 
+	content := truncate(redactSecrets(file, hunk.Content), 3000)
+	if file.LFS != nil {
+		content = lfsDiffText(file)
+	}
+
 	prompt := fmt.Sprintf(`You are a senior software engineer reviewing a code diff. Provide a concise 1-sentence summary of what this specific change does.
 
 File: %s (%s)
@@ -153,7 +174,7 @@ Hunk header: %s
 Diff content:
 %s
 
-Respond with ONLY the summary, no preamble or formatting.`, file.Path, file.Language, hunk.Header, truncate(hunk.Content, 3000))
+Respond with ONLY the summary, no preamble or formatting.`, file.Path, file.Language, hunk.Header, content)
 
 	return ai.complete(context.Background(), prompt)
 }
@@ -175,7 +196,7 @@ Diff:
 %s
 
 Respond with ONLY a JSON array of strings, each being one checklist item. Example:
-["Check that the SQL query uses parameterized arguments", "Verify error is propagated to caller"]`, file.Path, file.Status, file.Language, strings.Join(file.RiskReasons, ", "), truncate(file.RawDiff, 4000))
+["Check that the SQL query uses parameterized arguments", "Verify error is propagated to caller"]`, file.Path, file.Status, file.Language, strings.Join(file.RiskReasons, ", "), aiDiffText(file, 4000))
 
 	result, err := ai.complete(context.Background(), prompt)
 	if err != nil {
@@ -202,6 +223,42 @@ Respond with ONLY a JSON array of strings, each being one checklist item. Exampl
 	return checklist, nil
 }
 
+// SuggestFixPatch asks the AI for a unified diff that addresses a review finding
+// or checklist item against the current contents of a file.
+func (ai *AIClient) SuggestFixPatch(ctx context.Context, file *DiffFile, issue string, current string) (string, error) {
+	if ai == nil {
+		return "", fmt.Errorf("no AI provider configured")
+	}
+
+	prompt := fmt.Sprintf(`You are a senior software engineer fixing an issue raised during code review.
+
+Produce a minimal unified diff (git diff format) against the CURRENT file contents below that resolves the issue. Do not change unrelated code.
+
+Rules:
+- Use "--- a/%s" and "+++ b/%s" as the file headers.
+- Every hunk must start with an "@@ -start,count +start,count @@" header.
+- Context lines must match the current file exactly, including indentation.
+- Respond with ONLY the diff, no explanation or markdown code fences.
+
+Issue: %s
+
+File: %s
+Language: %s
+
+Change under review:
+%s
+
+Current file contents:
+%s`, file.Path, file.Path, issue, file.Path, file.Language, aiDiffText(file, 3000), truncate(redactSecrets(file, current), 12000))
+
+	result, err := ai.complete(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	return extractPatch(result), nil
+}
+
 // complete sends a prompt to the configured AI provider and returns the response.
 func (ai *AIClient) complete(ctx context.Context, prompt string) (string, error) {
 	switch ai.provider {
@@ -477,6 +534,28 @@ func extractJSON(s string) string {
 	return s
 }
 
+// extractPatch strips markdown code fences and any preamble before the first diff header.
+func extractPatch(s string) string {
+	s = strings.TrimSpace(s)
+
+	s = strings.TrimPrefix(s, "```diff")
+	s = strings.TrimPrefix(s, "```patch")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	s = strings.TrimSpace(s)
+
+	for _, marker := range []string{"diff --git ", "--- "} {
+		if strings.HasPrefix(s, marker) {
+			return s
+		}
+		if idx := strings.Index(s, "\n"+marker); idx >= 0 {
+			return s[idx+1:]
+		}
+	}
+
+	return s
+}
+
 // extractJSONObject finds the outermost JSON object in a response.
 func extractJSONObject(s string) string {
 	s = strings.TrimSpace(s)
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FixSuggestion is an AI-proposed patch for a single review finding.
+type FixSuggestion struct {
+	Path        string      `json:"path"`
+	Issue       string      `json:"issue"`
+	Patch       string      `json:"patch"`
+	Preview     []*DiffFile `json:"preview"`
+	Applicable  bool        `json:"applicable"`
+	CheckOutput string      `json:"checkOutput,omitempty"`
+}
+
+// SuggestFix asks the AI for a patch that resolves issue in file, then validates
+// it against the working tree without modifying anything.
+func SuggestFix(repoPath string, file *DiffFile, issue string, ai *AIClient) (*FixSuggestion, error) {
+	const suggestTimeout = 90 * time.Second
+
+	issue = strings.TrimSpace(issue)
+	if issue == "" {
+		return nil, fmt.Errorf("issue is required")
+	}
+	if file.Status == "deleted" || file.Status == "binary" {
+		return nil, fmt.Errorf("cannot suggest a fix for a %s file", file.Status)
+	}
+	if file.LFS != nil {
+		return nil, fmt.Errorf("cannot suggest a fix for a Git LFS object")
+	}
+
+	current, err := readWorkingTreeFile(repoPath, file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), suggestTimeout)
+	defer cancel()
+
+	patch, err := ai.SuggestFixPatch(ctx, file, issue, current)
+	if err != nil {
+		return nil, err
+	}
+
+	patch = normalizeFixPatch(patch, file.Path)
+	if patch == "" {
+		return nil, fmt.Errorf("AI did not return a patch")
+	}
+
+	suggestion := &FixSuggestion{
+		Path:    file.Path,
+		Issue:   issue,
+		Patch:   patch,
+		Preview: parseDiffOutput(patch),
+	}
+	if suggestion.Preview == nil {
+		suggestion.Preview = []*DiffFile{}
+	}
+
+	if err := validateFixPatch(patch, file.Path); err != nil {
+		suggestion.CheckOutput = err.Error()
+		return suggestion, nil
+	}
+
+	if out, err := CheckPatch(repoPath, patch); err != nil {
+		suggestion.CheckOutput = strings.TrimSpace(out)
+		if suggestion.CheckOutput == "" {
+			suggestion.CheckOutput = err.Error()
+		}
+		return suggestion, nil
+	}
+
+	suggestion.Applicable = true
+	return suggestion, nil
+}
+
+// ApplyFix re-validates a previously suggested patch and applies it to the working tree.
+func ApplyFix(repoPath string, path string, patch string) error {
+	patch = normalizeFixPatch(patch, path)
+	if patch == "" {
+		return fmt.Errorf("patch is required")
+	}
+	if err := validateFixPatch(patch, path); err != nil {
+		return err
+	}
+	if _, err := CheckPatch(repoPath, patch); err != nil {
+		return fmt.Errorf("patch no longer applies: %w", err)
+	}
+
+	if _, err := runGitWithInput(repoPath, patch, "apply", "--recount", "--whitespace=nowarn", "-"); err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	return nil
+}
+
+// CheckPatch runs git apply --check against the working tree.
+func CheckPatch(repoPath string, patch string) (string, error) {
+	return runGitWithInput(repoPath, patch, "apply", "--check", "--recount", "--whitespace=nowarn", "-")
+}
+
+// normalizeFixPatch ensures the patch has a diff --git header so it can be
+// previewed with parseDiffOutput, and that it ends with a newline for git apply.
+func normalizeFixPatch(patch string, path string) string {
+	patch = strings.ReplaceAll(patch, "\r\n", "\n")
+	patch = strings.TrimSpace(patch)
+	if patch == "" {
+		return ""
+	}
+
+	if !strings.HasPrefix(patch, "diff --git ") {
+		patch = fmt.Sprintf("diff --git a/%s b/%s\n%s", path, path, patch)
+	}
+
+	return patch + "\n"
+}
+
+// validateFixPatch rejects patches that touch anything other than the target file.
+// Only the ---/+++ lines of a file header are checked; inside a hunk they are
+// removed or added lines such as SQL comments.
+func validateFixPatch(patch string, path string) error {
+	inHeader := true
+	for _, line := range strings.Split(patch, "\n") {
+		var target string
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if line != fmt.Sprintf("diff --git a/%s b/%s", path, path) {
+				return fmt.Errorf("patch must only modify %s", path)
+			}
+			inHeader = true
+			continue
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+			continue
+		case !inHeader:
+			continue
+		case strings.HasPrefix(line, "--- "):
+			target = strings.TrimSpace(strings.TrimPrefix(line, "--- "))
+		case strings.HasPrefix(line, "+++ "):
+			target = strings.TrimSpace(strings.TrimPrefix(line, "+++ "))
+		default:
+			continue
+		}
+
+		if target != "a/"+path && target != "b/"+path {
+			return fmt.Errorf("patch must only modify %s, found %s", path, target)
+		}
+	}
+	return nil
+}
+
+// readWorkingTreeFile reads a repository-relative file, refusing paths outside the repo.
+func readWorkingTreeFile(repoPath string, relPath string) (string, error) {
+	fullPath, err := resolveRepoFilePath(repoPath, relPath)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+	return string(content), nil
+}
+
+func resolveRepoFilePath(repoPath string, relPath string) (string, error) {
+	root, err := filepath.Abs(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repository path: %w", err)
+	}
+
+	fullPath := filepath.Join(root, filepath.FromSlash(relPath))
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || rel == ".." {
+		return "", fmt.Errorf("path %q is outside the repository", relPath)
+	}
+	return fullPath, nil
+}
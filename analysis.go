@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -11,75 +12,15 @@ import (
 	"time"
 )
 
-// riskPattern defines a pattern to match against file paths or diff content,
-// along with the risk score contribution and a human-readable reason.
-type riskPattern struct {
-	pathContains    []string // Any of these substrings in the file path
-	contentContains []string // Any of these substrings in the diff content
-	score           int
-	reason          string
-}
-
-// riskPatterns is the ordered list of heuristic patterns for risk scoring.
-var riskPatterns = []riskPattern{
-	{
-		pathContains: []string{"auth", "login", "session", "token", "oauth", "jwt", "credential", "password", "secret"},
-		score:        30,
-		reason:       "Touches authentication/authorization code",
-	},
-	{
-		pathContains: []string{"crypto", "encrypt", "decrypt", "hash", "cert", "tls", "ssl"},
-		score:        30,
-		reason:       "Touches cryptography/security code",
-	},
-	{
-		pathContains:    []string{"migration", "schema", "database", "db"},
-		contentContains: []string{"CREATE TABLE", "ALTER TABLE", "DROP TABLE", "CREATE INDEX", "DROP INDEX"},
-		score:           25,
-		reason:          "Database schema or migration change",
-	},
-	{
-		contentContains: []string{"SELECT ", "INSERT ", "UPDATE ", "DELETE ", "exec(", "raw(", "rawQuery", "execute("},
-		score:           20,
-		reason:          "Contains raw SQL or query execution",
-	},
-	{
-		pathContains: []string{"api/", "routes", "handler", "controller", "endpoint", "middleware"},
-		score:        20,
-		reason:       "Modifies public API surface or middleware",
-	},
-	{
-		pathContains: []string{"permission", "rbac", "role", "access", "policy", "acl"},
-		score:        25,
-		reason:       "Touches permission/access control logic",
-	},
-	{
-		contentContains: []string{"panic(", "os.Exit", "log.Fatal", "process.exit"},
-		score:           15,
-		reason:          "Contains abrupt termination calls",
-	},
-	{
-		pathContains: []string{".env", "config", "setting"},
-		score:        15,
-		reason:       "Configuration file change",
-	},
-	{
-		pathContains: []string{"docker", "k8s", "kubernetes", "deploy", "ci", "cd", "pipeline", "terraform", ".tf"},
-		score:        15,
-		reason:       "Infrastructure/deployment configuration change",
-	},
-	{
-		pathContains: []string{"payment", "billing", "invoice", "stripe", "subscription", "charge"},
-		score:        25,
-		reason:       "Touches payment/billing code",
-	},
-}
+// bugfixSignalRe matches words on changed lines that suggest a bug fix.
+var bugfixSignalRe = regexp.MustCompile(`(?i)\b(fix|fixes|fixed|bug|bugs|bugfix|hotfix|patch|workaround)\b`)
 
 // AnalyzeDiff performs risk scoring and semantic grouping on all files in the diff.
 // It sorts files by risk score (highest first) after analysis.
 func AnalyzeDiff(data *DiffData, ai *AIClient) {
+	rules := data.effectiveRiskRules()
 	for _, file := range data.Files {
-		scoreFileRiskHeuristic(file)
+		scoreFileRiskHeuristic(file, rules)
 		classifySemanticGroupHeuristic(file)
 	}
 
@@ -96,8 +37,9 @@ func AnalyzeDiff(data *DiffData, ai *AIClient) {
 // AnalyzeDiffHeuristics runs only semantic grouping for fast response.
 // Risk analysis is done by AI in the background.
 func AnalyzeDiffHeuristics(data *DiffData) {
+	rules := data.effectiveRiskRules()
 	for _, file := range data.Files {
-		scoreFileRiskHeuristic(file)
+		scoreFileRiskHeuristic(file, rules)
 		classifySemanticGroupHeuristic(file)
 	}
 }
@@ -136,42 +78,35 @@ func AnalyzeDiffAI(data *DiffData, ai *AIClient, holder *DiffHolder) {
 	holder.Replace(data)
 }
 
-// scoreFileRisk calculates a risk score for a file based on heuristic patterns.
-func scoreFileRiskHeuristic(file *DiffFile) {
-	pathLower := strings.ToLower(file.Path)
-	contentLower := strings.ToLower(file.RawDiff)
+// scoreFileRisk calculates a risk score for a file from its path rules, the
+// scores of its hunks and structural signals such as size, deletions and
+// mode changes.
+func scoreFileRiskHeuristic(file *DiffFile, rules *RiskRuleSet) {
 	score := 0
 	var reasons []string
 
-	for _, pattern := range riskPatterns {
-		matched := false
-
-		// Check path patterns
-		for _, p := range pattern.pathContains {
-			if strings.Contains(pathLower, p) {
-				matched = true
-				break
-			}
-		}
-
-		// Check content patterns (only if path didn't match)
-		if !matched {
-			for _, c := range pattern.contentContains {
-				if strings.Contains(contentLower, strings.ToLower(c)) {
-					matched = true
-					break
-				}
-			}
-		}
+	file.RiskEvidence = nil
+	file.Secrets, file.secretValues = rules.scanSecrets(file)
+	pathMatches := rules.matchPathRules(file)
+	for _, m := range pathMatches {
+		score += m.rule.Score
+		reasons = append(reasons, m.rule.Reason)
+		file.RiskEvidence = append(file.RiskEvidence, m.evidence...)
+	}
 
-		if matched {
-			score += pattern.score
-			reasons = append(reasons, pattern.reason)
-		}
+	hunkScore, hunkReasons, evidence := scoreHunksHeuristic(file, rules, pathMatches)
+	score += hunkScore
+	reasons = append(reasons, hunkReasons...)
+	for _, ev := range evidence {
+		file.RiskEvidence = mergeEvidence(file.RiskEvidence, ev)
 	}
 
-	// Bonus: large diffs are riskier (more surface area for bugs)
+	// Bonus: large diffs are riskier (more surface area for bugs). Not for
+	// generated code or the lines of an LFS pointer.
 	totalLines := file.LinesAdded + file.LinesRemoved
+	if file.Generated || file.LFS != nil {
+		totalLines = 0
+	}
 	if totalLines > 200 {
 		score += 15
 		reasons = append(reasons, "Large change (200+ lines)")
@@ -184,26 +119,68 @@ func scoreFileRiskHeuristic(file *DiffFile) {
 	}
 
 	// Penalty: deletions without additions (removing error handling, etc.)
-	if file.LinesRemoved > file.LinesAdded*2 && file.LinesRemoved > 10 {
+	if !file.Generated && file.LFS == nil && file.LinesRemoved > file.LinesAdded*2 && file.LinesRemoved > 10 {
 		score += 10
 		reasons = append(reasons, "Significant code removal")
 	}
 
-	// Check for removed error handling patterns
-	if strings.Contains(contentLower, "-\tif err") || strings.Contains(contentLower, "- if err") ||
-		strings.Contains(contentLower, "-\tcatch") || strings.Contains(contentLower, "- catch") ||
-		strings.Contains(contentLower, "-\texcept") || strings.Contains(contentLower, "- except") {
+	// Mode, symlink and submodule changes are easy to miss in a text diff
+	switch {
+	case file.Status == "submodule":
+		score += 20
+		reasons = append(reasons, "Updates submodule pointer")
+		if file.Submodule != nil && file.Submodule.Rewind {
+			score += 10
+			reasons = append(reasons, "Submodule pointer moves backwards or onto a diverged history")
+		}
+	case file.Status == "conflicted":
+		score += 40
+		reasons = append(reasons, "Has unresolved merge conflicts")
+	case file.Status == "typechange":
 		score += 15
-		reasons = append(reasons, "Removes error handling")
+		reasons = append(reasons, "Changes file type (e.g. regular file to symlink)")
+	case file.Status == "symlink":
+		score += 10
+		reasons = append(reasons, "Changes symlink target")
+	case file.OldMode != "" && file.NewMode != "" && file.OldMode != file.NewMode:
+		if file.NewMode == "100755" {
+			score += 15
+			reasons = append(reasons, "Makes file executable")
+		} else {
+			score += 5
+			reasons = append(reasons, "Changes file permissions")
+		}
+	case file.Status == "added" && file.NewMode == "100755":
+		score += 10
+		reasons = append(reasons, "Adds executable file")
 	}
 
-	// Cap score at 100
-	if score > 100 {
-		score = 100
+	if file.LFS != nil {
+		lfsScore, lfsReasons := lfsRisk(file)
+		score += lfsScore
+		reasons = append(reasons, lfsReasons...)
+	}
+	if len(file.APIChanges) > 0 {
+		apiScore, apiReasons := goAPIRisk(file)
+		score += apiScore
+		reasons = append(reasons, apiReasons...)
+	}
+	if len(file.Dependencies) > 0 {
+		depScore, depReasons := dependencyRisk(file)
+		score += depScore
+		reasons = append(reasons, depReasons...)
+	}
+	if file.Generated {
+		reasons = append(reasons, file.GeneratedReason)
+	}
+	if file.TooLarge {
+		reasons = append(reasons, "Diff too large to load; review it locally")
 	}
 
-	file.RiskScore = score
+	// Keep the score within 0-100; rules may lower it
+	file.RiskScore = clampRiskScore(score)
 	file.RiskReasons = reasons
+	applySecretRisk(file)
 }
 
 // classifySemanticGroup assigns a semantic category to a file based on its path and content.
@@ -211,6 +188,12 @@ func classifySemanticGroupHeuristic(file *DiffFile) {
 	pathLower := strings.ToLower(file.Path)
 	baseName := strings.ToLower(filepath.Base(file.Path))
 
+	// Generated, vendored and minified files
+	if file.Generated {
+		file.SemanticGroup = "generated"
+		return
+	}
+
 	// Test files
 	if strings.Contains(pathLower, "_test.") || strings.Contains(pathLower, ".test.") ||
 		strings.Contains(pathLower, ".spec.") || strings.Contains(pathLower, "test/") ||
@@ -239,7 +222,7 @@ func classifySemanticGroupHeuristic(file *DiffFile) {
 		"docker-compose.yml": true, "docker-compose.yaml": true,
 	}
 
-	if configExts[ext] || configFiles[baseName] ||
+	if configExts[ext] || configFiles[baseName] || file.Status == "submodule" ||
 		strings.Contains(pathLower, "config/") || strings.Contains(pathLower, ".github/") {
 		file.SemanticGroup = "config"
 		return
@@ -251,12 +234,9 @@ func classifySemanticGroupHeuristic(file *DiffFile) {
 		return
 	}
 
-	// For source code files, try to classify by diff content
-	contentLower := strings.ToLower(file.RawDiff)
-
-	// Bug fix signals
-	if strings.Contains(contentLower, "fix") || strings.Contains(contentLower, "bug") ||
-		strings.Contains(contentLower, "patch") || strings.Contains(contentLower, "hotfix") {
+	// For source code files, try to classify by the changed lines. Context
+	// and words like "prefix" or "debug" say nothing about the change.
+	if hasChangedLineMatching(file, bugfixSignalRe) {
 		file.SemanticGroup = "bugfix"
 		return
 	}
@@ -286,6 +266,18 @@ func classifySemanticGroupHeuristic(file *DiffFile) {
 	file.SemanticGroup = "feature"
 }
 
+// hasChangedLineMatching reports whether an added or removed line matches re.
+func hasChangedLineMatching(file *DiffFile, re *regexp.Regexp) bool {
+	for _, h := range file.Hunks {
+		for _, line := range h.Lines {
+			if line.Kind != "context" && re.MatchString(line.Content) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func enrichRiskWithAI(files []*DiffFile, ai *AIClient) error {
 	const preflightTimeout = 4 * time.Second
 	const perFileTimeout = 25 * time.Second
@@ -327,6 +319,9 @@ func enrichRiskWithAI(files []*DiffFile, ai *AIClient) error {
 		if failFast.Load() {
 			break
 		}
+		if file.Collapsed || file.TooLarge || (file.Generated && !ai.IncludeGenerated()) {
+			continue
+		}
 
 		wg.Add(1)
 		sem <- struct{}{}
@@ -367,9 +362,20 @@ func enrichRiskWithAI(files []*DiffFile, ai *AIClient) error {
 			} else {
 				f.RiskReasons = []string{"No specific risks identified"}
 			}
+			for _, hr := range assessment.Hunks {
+				if hr.Index < 0 || hr.Index >= len(f.Hunks) {
+					continue
+				}
+				h := f.Hunks[hr.Index]
+				h.RiskScore = clampRiskScore(hr.RiskScore)
+				if reason := strings.TrimSpace(hr.Reason); reason != "" {
+					h.RiskReasons = []string{reason}
+				}
+			}
+			applySecretRisk(f)
 
 			group := normalizeSemanticGroup(assessment.SemanticGroup)
-			if group != "" {
+			if group != "" && !f.Generated {
 				f.SemanticGroup = group
 			}
 		}(file)
@@ -440,7 +446,7 @@ func mergeReasons(primary []string, secondary []string) []string {
 func normalizeSemanticGroup(group string) string {
 	group = strings.ToLower(strings.TrimSpace(group))
 	switch group {
-	case "feature", "bugfix", "refactor", "test", "config", "docs", "style":
+	case "feature", "bugfix", "refactor", "test", "config", "docs", "style", "generated":
 		return group
 	default:
 		return ""
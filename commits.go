@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CommitInfo describes one commit of the range under review.
+type CommitInfo struct {
+	SHA          string   `json:"sha"`
+	ShortSHA     string   `json:"shortSha"`
+	Author       string   `json:"author"`
+	AuthorEmail  string   `json:"authorEmail"`
+	Date         string   `json:"date"` // Author date, ISO 8601
+	Subject      string   `json:"subject"`
+	Parents      []string `json:"parents"`
+	FilesChanged int      `json:"filesChanged"`
+	LinesAdded   int      `json:"linesAdded"`
+	LinesRemoved int      `json:"linesRemoved"`
+}
+
+// CommitRange selects the commits First..Last (inclusive, oldest first) for
+// review instead of the whole base...head diff. First == Last reviews a single commit.
+type CommitRange struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+}
+
+var shortStatRe = regexp.MustCompile(`(\d+) files? changed(?:, (\d+) insertions?\(\+\))?(?:, (\d+) deletions?\(-\))?`)
+
+// ListRangeCommits lists the commits reachable from head but not from base, oldest first.
+func ListRangeCommits(repoPath string, base string, head string) ([]CommitInfo, error) {
+	out, err := runGit(repoPath, "log", "--reverse", "--shortstat", "--no-color", "--no-ext-diff",
+		"--format=%x1e%H%x1f%h%x1f%an%x1f%ae%x1f%aI%x1f%P%x1f%s", base+".."+head, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	commits := []CommitInfo{}
+	for _, record := range strings.Split(out, "\x1e") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		header, stat, _ := strings.Cut(record, "\n")
+		fields := strings.Split(header, "\x1f")
+		if len(fields) < 7 {
+			continue
+		}
+
+		commit := CommitInfo{
+			SHA:         fields[0],
+			ShortSHA:    fields[1],
+			Author:      fields[2],
+			AuthorEmail: fields[3],
+			Date:        fields[4],
+			Parents:     strings.Fields(fields[5]),
+			Subject:     fields[6],
+		}
+		if m := shortStatRe.FindStringSubmatch(stat); m != nil {
+			commit.FilesChanged, _ = strconv.Atoi(m[1])
+			commit.LinesAdded, _ = strconv.Atoi(m[2])
+			commit.LinesRemoved, _ = strconv.Atoi(m[3])
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// resolveCommit returns the full SHA of a commit-ish.
+func resolveCommit(repoPath string, ref string) (string, error) {
+	out, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown commit %q", ref)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// commitRangeBase returns the tree-ish to diff First..Last against: the first
+// parent of First, or the empty tree for a root commit.
+func commitRangeBase(repoPath string, first string) (string, error) {
+	if parent, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", first+"^1"); err == nil {
+		return strings.TrimSpace(parent), nil
+	}
+	emptyTree, err := runGit(repoPath, "hash-object", "-t", "tree", "/dev/null")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve empty tree: %w", err)
+	}
+	return strings.TrimSpace(emptyTree), nil
+}
+
+// StepCommit moves the selection one commit forward or back through commits.
+// With no selection, "next" starts at the oldest commit and "prev" at the newest.
+func StepCommit(commits []CommitInfo, current *CommitRange, direction string) (*CommitRange, error) {
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits in range")
+	}
+
+	index := func(sha string) int {
+		for i, c := range commits {
+			if c.SHA == sha {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var target int
+	switch direction {
+	case "next":
+		target = 0
+		if current != nil {
+			if i := index(current.Last); i >= 0 {
+				target = i + 1
+			} else {
+				target = -1
+			}
+		}
+	case "prev":
+		target = len(commits) - 1
+		if current != nil {
+			if i := index(current.First); i >= 0 {
+				target = i - 1
+			} else {
+				target = -1
+			}
+		}
+	default:
+		return nil, fmt.Errorf("direction must be next or prev")
+	}
+
+	if target < 0 || target >= len(commits) {
+		return nil, fmt.Errorf("no %s commit in range", direction)
+	}
+	sha := commits[target].SHA
+	return &CommitRange{First: sha, Last: sha}, nil
+}
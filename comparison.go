@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Comparison modes for diffing the base ref against something.
+const (
+	compareThreeDot = "three-dot" // Head's changes since it diverged from base (default)
+	compareTwoDot   = "two-dot"   // Everything that differs between the base and head tips
+	compareWorktree = "worktree"  // Base against the working tree, including untracked files
+	compareIndex    = "index"     // Base against the index
+)
+
+// diffComparison is the configured diff mode resolved to git arguments,
+// file sources and the commits involved.
+type diffComparison struct {
+	Mode      string   // Comparison mode, empty for staged/unstaged/commit review
+	Args      []string // git diff arguments selecting the two sides
+	Untracked bool     // Whether untracked files belong on the new side
+	Old       fileSource
+	New       fileSource
+	BaseLabel string
+	HeadLabel string
+	BaseSHA   string
+	HeadSHA   string
+	MergeBase string
+	Notice    string // Caveat to show with the diff
+}
+
+// resetReviewTarget drops commit, range-diff, stash and patch selections so
+// the diff goes back to the base/head, staged or unstaged view.
+func (cfg *Config) resetReviewTarget() {
+	cfg.Commits = nil
+	cfg.RangeDiff = nil
+	cfg.Stash = ""
+	cfg.Patch = nil
+}
+
+func validateCompareMode(mode string) error {
+	switch mode {
+	case "", compareThreeDot, compareTwoDot, compareWorktree, compareIndex:
+		return nil
+	}
+	return fmt.Errorf("invalid comparison mode %q (use three-dot, two-dot, worktree or index)", mode)
+}
+
+// resolveComparison works out what the current configuration compares.
+// Imported patches have no comparison; staged/unstaged, stash, range-diff and
+// commit review take precedence over the mode.
+func resolveComparison(cfg *Config) (diffComparison, error) {
+	headSHA := func() string {
+		sha, _ := resolveCommit(cfg.RepoPath, "HEAD")
+		return sha
+	}
+
+	switch {
+	case cfg.Patch != nil:
+		return diffComparison{}, fmt.Errorf("file contents are not available for imported patches")
+	case cfg.Staged && cfg.Unstaged:
+		return diffComparison{
+			Args:      []string{"HEAD"},
+			Untracked: true,
+			Old:       fileSource{Kind: "ref", Ref: "HEAD"},
+			New:       fileSource{Kind: "worktree"},
+			BaseLabel: "HEAD",
+			HeadLabel: "working tree",
+			BaseSHA:   headSHA(),
+		}, nil
+	case cfg.Staged:
+		return diffComparison{
+			Args:      []string{"--staged"},
+			Old:       fileSource{Kind: "ref", Ref: "HEAD"},
+			New:       fileSource{Kind: "index"},
+			BaseLabel: "staged",
+			HeadLabel: "index",
+			BaseSHA:   headSHA(),
+		}, nil
+	case cfg.Unstaged:
+		return diffComparison{
+			Untracked: true,
+			Old:       fileSource{Kind: "index"},
+			New:       fileSource{Kind: "worktree"},
+			BaseLabel: "index",
+			HeadLabel: "working tree",
+		}, nil
+	case cfg.Stash != "":
+		return stashComparison(cfg.RepoPath, cfg.Stash)
+	case cfg.RangeDiff != nil:
+		return rangeDiffComparison(cfg.RepoPath, *cfg.RangeDiff)
+	case cfg.Commits != nil:
+		from, err := commitRangeBase(cfg.RepoPath, cfg.Commits.First)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		return diffComparison{
+			Args:      []string{from, cfg.Commits.Last},
+			Old:       fileSource{Kind: "ref", Ref: from},
+			New:       fileSource{Kind: "ref", Ref: cfg.Commits.Last},
+			BaseLabel: cfg.Commits.First + "^",
+			HeadLabel: cfg.Commits.Last,
+			BaseSHA:   from,
+			HeadSHA:   cfg.Commits.Last,
+		}, nil
+	}
+
+	base, err := resolveCommit(cfg.RepoPath, cfg.Base)
+	if err != nil {
+		return diffComparison{}, fmt.Errorf("base: %w", err)
+	}
+
+	switch cfg.Mode {
+	case compareWorktree:
+		return diffComparison{
+			Mode:      compareWorktree,
+			Args:      []string{base},
+			Untracked: true,
+			Old:       fileSource{Kind: "ref", Ref: base},
+			New:       fileSource{Kind: "worktree"},
+			BaseLabel: cfg.Base,
+			HeadLabel: "working tree",
+			BaseSHA:   base,
+		}, nil
+	case compareIndex:
+		return diffComparison{
+			Mode:      compareIndex,
+			Args:      []string{"--cached", base},
+			Old:       fileSource{Kind: "ref", Ref: base},
+			New:       fileSource{Kind: "index"},
+			BaseLabel: cfg.Base,
+			HeadLabel: "index",
+			BaseSHA:   base,
+		}, nil
+	}
+
+	head, err := resolveCommit(cfg.RepoPath, cfg.Head)
+	if err != nil {
+		return diffComparison{}, fmt.Errorf("head: %w", err)
+	}
+
+	if cfg.Mode == compareTwoDot {
+		return diffComparison{
+			Mode:      compareTwoDot,
+			Args:      []string{base, head},
+			Old:       fileSource{Kind: "ref", Ref: base},
+			New:       fileSource{Kind: "ref", Ref: head},
+			BaseLabel: cfg.Base,
+			HeadLabel: cfg.Head,
+			BaseSHA:   base,
+			HeadSHA:   head,
+		}, nil
+	}
+
+	mergeBase, err := runGit(cfg.RepoPath, "merge-base", base, head)
+	if err != nil {
+		return diffComparison{}, fmt.Errorf("failed to compute merge base of %s and %s: %w", cfg.Base, cfg.Head, err)
+	}
+	mergeBase = strings.TrimSpace(mergeBase)
+	return diffComparison{
+		Mode:      compareThreeDot,
+		Args:      []string{mergeBase, head},
+		Old:       fileSource{Kind: "ref", Ref: mergeBase},
+		New:       fileSource{Kind: "ref", Ref: head},
+		BaseLabel: cfg.Base,
+		HeadLabel: cfg.Head,
+		BaseSHA:   base,
+		HeadSHA:   head,
+		MergeBase: mergeBase,
+	}, nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Operations that can stop with conflicts.
+const (
+	opMerge      = "merge"
+	opRebase     = "rebase"
+	opCherryPick = "cherry-pick"
+)
+
+// ConflictState is the operation in progress and the files it left unmerged.
+type ConflictState struct {
+	Operation   string         `json:"operation"`   // merge, rebase, cherry-pick; empty when none is in progress
+	OursLabel   string         `json:"oursLabel"`   // What "ours" means for the operation
+	TheirsLabel string         `json:"theirsLabel"` // What "theirs" means for the operation
+	Files       []ConflictFile `json:"files"`
+}
+
+// ConflictFile is one unmerged path and which merge stages it has.
+type ConflictFile struct {
+	Path      string `json:"path"`
+	HasBase   bool   `json:"hasBase"`
+	HasOurs   bool   `json:"hasOurs"`   // False when deleted on our side
+	HasTheirs bool   `json:"hasTheirs"` // False when deleted on their side
+	Blocks    int    `json:"blocks"`    // Conflict blocks left in the working tree file
+}
+
+// ConflictBlock is one <<<<<<< ... >>>>>>> region of a conflicted file.
+type ConflictBlock struct {
+	Index       int      `json:"index"`
+	StartLine   int      `json:"startLine"` // 1-based line of the <<<<<<< marker
+	EndLine     int      `json:"endLine"`   // 1-based line of the >>>>>>> marker
+	OursLabel   string   `json:"oursLabel"`
+	TheirsLabel string   `json:"theirsLabel"`
+	Ours        []string `json:"ours"`
+	Base        []string `json:"base,omitempty"` // Only with merge.conflictStyle diff3 or zdiff3
+	Theirs      []string `json:"theirs"`
+}
+
+// ConflictFileDetail holds the three versions of a conflicted file and the
+// working tree copy with its conflict blocks.
+type ConflictFileDetail struct {
+	Path   string          `json:"path"`
+	Base   *string         `json:"base"` // nil when the stage is missing
+	Ours   *string         `json:"ours"`
+	Theirs *string         `json:"theirs"`
+	Merged string          `json:"merged"`
+	Blocks []ConflictBlock `json:"blocks"`
+}
+
+// ConflictResolution resolves a whole file or a single block of it.
+type ConflictResolution struct {
+	Path    string `json:"path"`
+	Block   *int   `json:"block,omitempty"`   // Block index; nil resolves the whole file
+	Choice  string `json:"choice"`            // ours, theirs, both, edited
+	Content string `json:"content,omitempty"` // With "edited": the block's replacement lines, or the whole file
+}
+
+// DetectOperation reports which of merge, rebase or cherry-pick is in progress.
+func DetectOperation(repoPath string) string {
+	exists := func(name string) bool {
+		out, err := runGit(repoPath, "rev-parse", "--git-path", name)
+		if err != nil {
+			return false
+		}
+		path := strings.TrimSpace(out)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repoPath, path)
+		}
+		_, err = os.Stat(path)
+		return err == nil
+	}
+
+	switch {
+	case exists("rebase-merge") || exists("rebase-apply"):
+		return opRebase
+	case exists("MERGE_HEAD"):
+		return opMerge
+	case exists("CHERRY_PICK_HEAD"):
+		return opCherryPick
+	}
+	return ""
+}
+
+// GetConflictState lists the unmerged files of the operation in progress.
+func GetConflictState(repoPath string) (ConflictState, error) {
+	state := ConflictState{
+		Operation: DetectOperation(repoPath),
+		Files:     []ConflictFile{},
+	}
+	switch state.Operation {
+	case opRebase:
+		// Rebase replays your commits onto the upstream, so the sides swap
+		state.OursLabel, state.TheirsLabel = "upstream", "your commit"
+	case opMerge:
+		state.OursLabel, state.TheirsLabel = "current branch", "merged branch"
+	case opCherryPick:
+		state.OursLabel, state.TheirsLabel = "current branch", "picked commit"
+	}
+
+	out, err := runGit(repoPath, "ls-files", "-u", "-z")
+	if err != nil {
+		return state, fmt.Errorf("failed to list unmerged files: %w", err)
+	}
+
+	byPath := map[string]*ConflictFile{}
+	for _, record := range splitGitNulls(out) {
+		// <mode> SP <object> SP <stage> TAB <path>
+		meta, path, ok := strings.Cut(record, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 {
+			continue
+		}
+		file, ok := byPath[path]
+		if !ok {
+			state.Files = append(state.Files, ConflictFile{Path: path})
+			file = &state.Files[len(state.Files)-1]
+			byPath[path] = file
+		}
+		switch fields[2] {
+		case "1":
+			file.HasBase = true
+		case "2":
+			file.HasOurs = true
+		case "3":
+			file.HasTheirs = true
+		}
+	}
+
+	for i := range state.Files {
+		if content, err := readWorkingTreeFile(repoPath, state.Files[i].Path); err == nil {
+			state.Files[i].Blocks = len(parseConflictBlocks(content))
+		}
+	}
+	return state, nil
+}
+
+// GetConflictFile loads the base, ours and theirs versions of path along with
+// the working tree copy.
+func GetConflictFile(repoPath string, path string) (ConflictFileDetail, error) {
+	detail := ConflictFileDetail{Path: path, Blocks: []ConflictBlock{}}
+
+	stages := []**string{&detail.Base, &detail.Ours, &detail.Theirs}
+	for i, target := range stages {
+		content, exists, err := readFileVersion(repoPath, fileSource{Kind: "index", Stage: i + 1}, path)
+		if err != nil {
+			return ConflictFileDetail{}, err
+		}
+		if exists {
+			*target = &content
+		}
+	}
+
+	content, exists, err := readFileVersion(repoPath, fileSource{Kind: "worktree"}, path)
+	if err != nil {
+		return ConflictFileDetail{}, err
+	}
+	if exists {
+		detail.Merged = content
+		detail.Blocks = parseConflictBlocks(content)
+	}
+	return detail, nil
+}
+
+// ResolveConflict applies a resolution and stages the file once no conflict
+// blocks are left in it. It returns the number of blocks remaining.
+func ResolveConflict(repoPath string, res ConflictResolution) (int, error) {
+	switch res.Choice {
+	case "ours", "theirs", "both", "edited":
+	default:
+		return 0, fmt.Errorf("choice must be ours, theirs, both or edited")
+	}
+
+	fullPath, err := resolveRepoFilePath(repoPath, res.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	if res.Block == nil && (res.Choice == "ours" || res.Choice == "theirs") {
+		return 0, resolveWholeFile(repoPath, res.Path, res.Choice)
+	}
+
+	var content string
+	if res.Block == nil && res.Choice == "edited" {
+		content = res.Content
+	} else {
+		current, err := readWorkingTreeFile(repoPath, res.Path)
+		if err != nil {
+			return 0, err
+		}
+		blocks := parseConflictBlocks(current)
+		if len(blocks) == 0 {
+			return 0, fmt.Errorf("%s has no conflict blocks; resolve it with ours or theirs", res.Path)
+		}
+		if res.Block != nil && (*res.Block < 0 || *res.Block >= len(blocks)) {
+			return 0, fmt.Errorf("block %d out of range (file has %d)", *res.Block, len(blocks))
+		}
+		content = replaceConflictBlocks(current, blocks, res)
+	}
+
+	if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", res.Path, err)
+	}
+
+	remaining := len(parseConflictBlocks(content))
+	if remaining == 0 {
+		if err := StageFile(repoPath, res.Path); err != nil {
+			return 0, err
+		}
+	}
+	return remaining, nil
+}
+
+// resolveWholeFile takes one side's version of path, deleting the file when
+// that side deleted it.
+func resolveWholeFile(repoPath string, path string, choice string) error {
+	stage := "2"
+	if choice == "theirs" {
+		stage = "3"
+	}
+	if _, err := runGit(repoPath, "cat-file", "-e", ":"+stage+":"+path); err != nil {
+		if _, err := runGit(repoPath, "rm", "--quiet", "--force", "--ignore-unmatch", "--", path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil
+	}
+
+	if _, err := runGit(repoPath, "checkout", "--"+choice, "--", path); err != nil {
+		return fmt.Errorf("failed to check out %s version of %s: %w", choice, path, err)
+	}
+	return StageFile(repoPath, path)
+}
+
+// ContinueOperation commits the resolution and carries on with the operation.
+// A rebase may stop again on the next commit; the caller checks the state.
+func ContinueOperation(repoPath string) (string, error) {
+	state, err := GetConflictState(repoPath)
+	if err != nil {
+		return "", err
+	}
+	if len(state.Files) > 0 {
+		return "", fmt.Errorf("%d file(s) still have unresolved conflicts", len(state.Files))
+	}
+
+	// Keep the prepared commit messages instead of opening an editor
+	env := []string{"GIT_EDITOR=true"}
+	var out string
+	switch state.Operation {
+	case opMerge:
+		out, err = runGitEnv(repoPath, env, "commit", "--no-edit")
+	case opRebase:
+		out, err = runGitEnv(repoPath, env, "rebase", "--continue")
+	case opCherryPick:
+		out, err = runGitEnv(repoPath, env, "cherry-pick", "--continue")
+	default:
+		return "", fmt.Errorf("no merge, rebase or cherry-pick in progress")
+	}
+	if err != nil {
+		return out, fmt.Errorf("failed to continue %s: %w", state.Operation, err)
+	}
+	return out, nil
+}
+
+// AbortOperation abandons the operation in progress and restores the branch.
+func AbortOperation(repoPath string) (string, error) {
+	operation := DetectOperation(repoPath)
+	if operation == "" {
+		return "", fmt.Errorf("no merge, rebase or cherry-pick in progress")
+	}
+	out, err := runGit(repoPath, operation, "--abort")
+	if err != nil {
+		return out, fmt.Errorf("failed to abort %s: %w", operation, err)
+	}
+	return out, nil
+}
+
+// parseConflictBlocks finds the conflict marker regions in content.
+func parseConflictBlocks(content string) []ConflictBlock {
+	blocks := []ConflictBlock{}
+	lines := strings.Split(content, "\n")
+
+	var block *ConflictBlock
+	section := ""
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case isConflictMarker(line, "<<<<<<<"):
+			block = &ConflictBlock{
+				Index:     len(blocks),
+				StartLine: i + 1,
+				OursLabel: strings.TrimSpace(line[7:]),
+				Ours:      []string{},
+				Theirs:    []string{},
+			}
+			section = "ours"
+		case block == nil:
+		case isConflictMarker(line, "|||||||") && section == "ours":
+			block.Base = []string{}
+			section = "base"
+		case line == "=======" && section != "theirs":
+			section = "theirs"
+		case isConflictMarker(line, ">>>>>>>") && section == "theirs":
+			block.EndLine = i + 1
+			block.TheirsLabel = strings.TrimSpace(line[7:])
+			blocks = append(blocks, *block)
+			block = nil
+		case section == "ours":
+			block.Ours = append(block.Ours, line)
+		case section == "base":
+			block.Base = append(block.Base, line)
+		default:
+			block.Theirs = append(block.Theirs, line)
+		}
+	}
+	return blocks
+}
+
+func isConflictMarker(line string, marker string) bool {
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+// replaceConflictBlocks substitutes the chosen side for the selected block,
+// or for every block when res.Block is nil.
+func replaceConflictBlocks(content string, blocks []ConflictBlock, res ConflictResolution) string {
+	lines := strings.Split(content, "\n")
+	crlf := strings.Contains(content, "\r\n")
+
+	// Work backwards so earlier line numbers stay valid
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+		if res.Block != nil && *res.Block != b.Index {
+			continue
+		}
+
+		var replacement []string
+		switch res.Choice {
+		case "ours":
+			replacement = b.Ours
+		case "theirs":
+			replacement = b.Theirs
+		case "both":
+			replacement = append(append([]string{}, b.Ours...), b.Theirs...)
+		case "edited":
+			replacement = splitFileLines(strings.ReplaceAll(res.Content, "\r\n", "\n"))
+		}
+		if crlf {
+			replacement = append([]string{}, replacement...)
+			for j := range replacement {
+				replacement[j] += "\r"
+			}
+		}
+
+		tail := append([]string{}, lines[b.EndLine:]...)
+		lines = append(append(lines[:b.StartLine-1], replacement...), tail...)
+	}
+	return strings.Join(lines, "\n")
+}
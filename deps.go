@@ -0,0 +1,765 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DependencyChange is a dependency added, removed or moved to another
+// version by a manifest or lockfile change.
+type DependencyChange struct {
+	Name       string `json:"name"`
+	OldName    string `json:"oldName,omitempty"` // The module path before a Go major version move
+	Ecosystem  string `json:"ecosystem"`         // go, npm, pypi, cargo
+	Manifest   string `json:"manifest"`          // The file the change was found in
+	Change     string `json:"change"`            // added, removed, upgraded, downgraded, changed
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"` // Version or requirement; empty when unpinned
+	Direct     bool   `json:"direct"`               // Declared by the project rather than pulled in
+	Dev        bool   `json:"dev,omitempty"`        // Only needed for development and tests
+	Major      bool   `json:"major,omitempty"`      // Crosses a major version, or a minor one below 1.0
+}
+
+// DependencySummary collects the dependency changes of the whole diff.
+type DependencySummary struct {
+	Changes    []DependencyChange `json:"changes"`
+	Added      int                `json:"added"`
+	Removed    int                `json:"removed"`
+	Upgraded   int                `json:"upgraded"`
+	Downgraded int                `json:"downgraded"`
+	MajorBumps int                `json:"majorBumps"`
+	NewDirect  int                `json:"newDirect"`
+	Lockfiles  []string           `json:"lockfiles"` // Lockfiles collapsed in favour of the changes listed here
+}
+
+// depEntry is a dependency as one version of a manifest declares it.
+type depEntry struct {
+	version string // Lockfiles with several versions of a package join them with ", "
+	direct  bool
+	dev     bool
+}
+
+// depFormat reads one kind of manifest or lockfile.
+type depFormat struct {
+	ecosystem string
+	lockfile  bool
+	manifest  string // For lockfiles, the manifest next to them that declares the direct dependencies
+	parse     func(content string) (map[string]depEntry, error)
+}
+
+// dependencyFormat returns the format of a dependency file, by base name.
+func dependencyFormat(filePath string) (depFormat, bool) {
+	base := strings.ToLower(path.Base(filePath))
+	switch {
+	case base == "go.mod":
+		return depFormat{ecosystem: "go", parse: parseGoModDeps}, true
+	case base == "go.sum":
+		return depFormat{ecosystem: "go", lockfile: true, manifest: "go.mod", parse: parseGoSumDeps}, true
+	case base == "package.json":
+		return depFormat{ecosystem: "npm", parse: parsePackageJSONDeps}, true
+	case base == "package-lock.json" || base == "npm-shrinkwrap.json":
+		return depFormat{ecosystem: "npm", lockfile: true, manifest: "package.json", parse: parsePackageLockDeps}, true
+	case base == "yarn.lock":
+		return depFormat{ecosystem: "npm", lockfile: true, manifest: "package.json", parse: parseYarnLockDeps}, true
+	case base == "pnpm-lock.yaml":
+		return depFormat{ecosystem: "npm", lockfile: true, manifest: "package.json", parse: parsePnpmLockDeps}, true
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return depFormat{ecosystem: "pypi", parse: parseRequirementsDeps}, true
+	case base == "poetry.lock":
+		return depFormat{ecosystem: "pypi", lockfile: true, parse: parseTOMLPackageDeps}, true
+	case base == "cargo.toml":
+		return depFormat{ecosystem: "cargo", parse: parseCargoTomlDeps}, true
+	case base == "cargo.lock":
+		return depFormat{ecosystem: "cargo", lockfile: true, manifest: "Cargo.toml", parse: parseTOMLPackageDeps}, true
+	}
+	return depFormat{}, false
+}
+
+// detectDependencyChanges compares the base and head versions of changed
+// manifests and lockfiles. Lockfiles only report packages their manifest
+// change does not already show, take direct dependencies from the manifest
+// next to them, and are collapsed once parsed.
+func detectDependencyChanges(repoPath string, oldSrc, newSrc fileSource, files []*DiffFile) {
+	if repoPath == "" || oldSrc.Kind == "" || newSrc.Kind == "" {
+		return
+	}
+
+	declared := map[string]bool{} // By directory, ecosystem and name
+	var lockfiles []*DiffFile
+	for _, file := range files {
+		file.Dependencies = nil
+		format, ok := dependencyFormat(file.Path)
+		if !ok || file.Status == "binary" || file.LFS != nil || (file.Generated && !format.lockfile) {
+			continue
+		}
+
+		oldPath := file.Path
+		if file.OldPath != "" {
+			oldPath = file.OldPath
+		}
+		before, ok := readDependencies(repoPath, oldSrc, oldPath, file.Status == "added", format)
+		if !ok {
+			continue
+		}
+		after, ok := readDependencies(repoPath, newSrc, file.Path, file.Status == "deleted", format)
+		if !ok {
+			continue
+		}
+
+		file.Dependencies = diffDependencies(before, after, format.ecosystem, file.Path)
+		if format.lockfile {
+			lockfiles = append(lockfiles, file)
+			markDirectDependencies(repoPath, newSrc, file, format)
+			continue
+		}
+		for _, c := range file.Dependencies {
+			declared[path.Dir(file.Path)+"\x00"+c.Ecosystem+"\x00"+c.Name] = true
+		}
+	}
+
+	for _, file := range lockfiles {
+		kept := []DependencyChange{} // Not nil, to tell a parsed lockfile without changes
+		for _, c := range file.Dependencies {
+			if !declared[path.Dir(file.Path)+"\x00"+c.Ecosystem+"\x00"+c.Name] {
+				kept = append(kept, c)
+			}
+		}
+		file.Dependencies = kept
+		collapseFile(file)
+	}
+}
+
+// markDirectDependencies flags the lockfile changes to packages that the
+// head version of the lockfile's manifest declares.
+func markDirectDependencies(repoPath string, src fileSource, file *DiffFile, format depFormat) {
+	manifestPath := path.Join(path.Dir(file.Path), format.manifest)
+	manifestFormat, ok := dependencyFormat(manifestPath)
+	if !ok {
+		return
+	}
+	declared, ok := readDependencies(repoPath, src, manifestPath, false, manifestFormat)
+	if !ok {
+		return
+	}
+	for i := range file.Dependencies {
+		c := &file.Dependencies[i]
+		if entry, ok := declared[c.Name]; ok {
+			c.Direct, c.Dev = entry.direct, entry.dev
+		}
+	}
+}
+
+func readDependencies(repoPath string, src fileSource, filePath string, missing bool, format depFormat) (map[string]depEntry, bool) {
+	if missing {
+		return map[string]depEntry{}, true
+	}
+	content, exists, err := readFileVersion(repoPath, src, filePath)
+	if err != nil {
+		return nil, false
+	}
+	if !exists {
+		return map[string]depEntry{}, true
+	}
+	deps, err := format.parse(content)
+	if err != nil {
+		return nil, false
+	}
+	return deps, true
+}
+
+// diffDependencies lists the changes between two versions of a manifest, by name.
+func diffDependencies(before, after map[string]depEntry, ecosystem, manifest string) []DependencyChange {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []DependencyChange
+	for _, name := range names {
+		o, inOld := before[name]
+		n, inNew := after[name]
+		c := DependencyChange{Name: name, Ecosystem: ecosystem, Manifest: manifest, OldVersion: o.version, NewVersion: n.version}
+		switch {
+		case !inOld:
+			c.Change, c.Direct, c.Dev = "added", n.direct, n.dev
+		case !inNew:
+			c.Change, c.Direct, c.Dev = "removed", o.direct, o.dev
+		case o.version != n.version:
+			c.Change = versionChange(o.version, n.version)
+			c.Direct, c.Dev = n.direct, n.dev
+			c.Major = isMajorVersionChange(o.version, n.version)
+		default:
+			continue
+		}
+		changes = append(changes, c)
+	}
+	if ecosystem == "go" {
+		changes = pairGoMajorVersions(changes)
+	}
+	return changes
+}
+
+var goMajorSuffixRe = regexp.MustCompile(`(/v([2-9]|[1-9][0-9]+)|\.v[0-9]+)$`)
+
+// pairGoMajorVersions turns the removal of a module and the addition of its
+// next major version path, like example.com/mod and example.com/mod/v2, into
+// one major version change.
+func pairGoMajorVersions(changes []DependencyChange) []DependencyChange {
+	removed := map[string]int{}
+	for i, c := range changes {
+		if c.Change == "removed" {
+			removed[goMajorSuffixRe.ReplaceAllString(c.Name, "")] = i
+		}
+	}
+
+	drop := map[int]bool{}
+	for i := range changes {
+		c := &changes[i]
+		if c.Change != "added" {
+			continue
+		}
+		j, ok := removed[goMajorSuffixRe.ReplaceAllString(c.Name, "")]
+		if !ok || drop[j] {
+			continue
+		}
+		drop[j] = true
+		c.OldName, c.OldVersion = changes[j].Name, changes[j].OldVersion
+		c.Change, c.Major = "upgraded", true
+		if compareDepVersions(goMajorOf(changes[j].Name), goMajorOf(c.Name)) > 0 {
+			c.Change = "downgraded"
+		}
+	}
+
+	var kept []DependencyChange
+	for i, c := range changes {
+		if !drop[i] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// goMajorOf returns the major version a module path selects, as a version string.
+func goMajorOf(module string) string {
+	suffix := goMajorSuffixRe.FindString(module)
+	if suffix == "" {
+		return "1"
+	}
+	return strings.TrimLeft(suffix, "/.v")
+}
+
+var depVersionRe = regexp.MustCompile(`(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// parseDepVersion reads the first version number in a version or
+// requirement such as "v1.2.3", "^4.17" or ">=2.0,<3".
+func parseDepVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	if strings.Contains(v, ", ") {
+		return parts, false // Several locked versions
+	}
+	m := depVersionRe.FindStringSubmatch(v)
+	if m == nil {
+		return parts, false
+	}
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(m[i+1])
+	}
+	return parts, true
+}
+
+// compareDepVersions returns -1, 0 or 1; versions that cannot be read compare equal.
+func compareDepVersions(a, b string) int {
+	va, okA := parseDepVersion(a)
+	vb, okB := parseDepVersion(b)
+	if !okA || !okB {
+		return 0
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionChange(oldVersion, newVersion string) string {
+	switch compareDepVersions(oldVersion, newVersion) {
+	case -1:
+		return "upgraded"
+	case 1:
+		return "downgraded"
+	}
+	return "changed"
+}
+
+// isMajorVersionChange follows semver: the major version changes, or the
+// minor one while the major version is 0.
+func isMajorVersionChange(oldVersion, newVersion string) bool {
+	a, okA := parseDepVersion(oldVersion)
+	b, okB := parseDepVersion(newVersion)
+	if !okA || !okB {
+		return false
+	}
+	return a[0] != b[0] || (a[0] == 0 && a[1] != b[1])
+}
+
+// addLockedVersion records one locked version of a package; a package locked
+// at several versions keeps all of them, sorted.
+func addLockedVersion(deps map[string]depEntry, name, version string) {
+	if name == "" || version == "" {
+		return
+	}
+	entry, ok := deps[name]
+	if !ok {
+		deps[name] = depEntry{version: version}
+		return
+	}
+	versions := strings.Split(entry.version, ", ")
+	for _, v := range versions {
+		if v == version {
+			return
+		}
+	}
+	versions = append(versions, version)
+	sort.Strings(versions)
+	entry.version = strings.Join(versions, ", ")
+	deps[name] = entry
+}
+
+// parseGoModDeps reads the require directives of a go.mod file.
+func parseGoModDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	inBlock := false
+	for _, raw := range strings.Split(content, "\n") {
+		line, comment, _ := strings.Cut(raw, "//")
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case strings.HasPrefix(line, "require") && strings.TrimSpace(strings.TrimPrefix(line, "require")) == "(":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "require "), strings.HasPrefix(line, "require\t"):
+			line = strings.TrimSpace(line[len("require"):])
+		default:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		module := strings.Trim(fields[0], "\"`")
+		deps[module] = depEntry{version: fields[1], direct: !strings.Contains(comment, "indirect")}
+	}
+	return deps, nil
+}
+
+// parseGoSumDeps reads the module versions a go.sum file has checksums for.
+func parseGoSumDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		addLockedVersion(deps, fields[0], fields[1])
+	}
+	return deps, nil
+}
+
+// parsePackageJSONDeps reads the dependency sections of a package.json.
+func parsePackageJSONDeps(content string) (map[string]depEntry, error) {
+	var pkg struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return nil, fmt.Errorf("invalid package.json: %w", err)
+	}
+
+	deps := map[string]depEntry{}
+	for name, version := range pkg.DevDependencies {
+		deps[name] = depEntry{version: version, direct: true, dev: true}
+	}
+	for _, section := range []map[string]string{pkg.PeerDependencies, pkg.OptionalDependencies, pkg.Dependencies} {
+		for name, version := range section {
+			deps[name] = depEntry{version: version, direct: true}
+		}
+	}
+	return deps, nil
+}
+
+// parsePackageLockDeps reads package-lock.json, both the "packages" layout of
+// lockfile version 2 and later and the nested "dependencies" of version 1.
+func parsePackageLockDeps(content string) (map[string]depEntry, error) {
+	type lockedDep struct {
+		Version      string               `json:"version"`
+		Dependencies map[string]lockedDep `json:"dependencies"`
+	}
+	var lock struct {
+		Packages     map[string]lockedDep `json:"packages"`
+		Dependencies map[string]lockedDep `json:"dependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &lock); err != nil {
+		return nil, fmt.Errorf("invalid package-lock.json: %w", err)
+	}
+
+	deps := map[string]depEntry{}
+	if len(lock.Packages) > 0 {
+		for key, dep := range lock.Packages {
+			i := strings.LastIndex(key, "node_modules/")
+			if i < 0 {
+				continue // The root project or a workspace
+			}
+			addLockedVersion(deps, key[i+len("node_modules/"):], dep.Version)
+		}
+		return deps, nil
+	}
+
+	var walk func(map[string]lockedDep)
+	walk = func(nested map[string]lockedDep) {
+		for name, dep := range nested {
+			addLockedVersion(deps, name, dep.Version)
+			walk(dep.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return deps, nil
+}
+
+// parseYarnLockDeps reads yarn.lock, classic or Berry: entries start with an
+// unindented `"name@range", name@range:` line and hold a version field.
+func parseYarnLockDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	name := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line[0] != ' ' {
+			spec, _, _ := strings.Cut(strings.TrimSuffix(line, ":"), ",")
+			name = npmSpecName(strings.Trim(strings.TrimSpace(spec), "\""))
+			continue
+		}
+		field := strings.TrimSpace(line)
+		if name == "" || !strings.HasPrefix(field, "version") {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(field, "version"), ":"))
+		addLockedVersion(deps, name, strings.Trim(value, "\""))
+		name = ""
+	}
+	return deps, nil
+}
+
+// npmSpecName returns the package name of a spec such as "@scope/pkg@^1.0"
+// or "pkg@npm:^1.0".
+func npmSpecName(spec string) string {
+	if len(spec) < 2 {
+		return ""
+	}
+	at := strings.Index(spec[1:], "@")
+	if at < 0 {
+		return ""
+	}
+	return spec[:at+1]
+}
+
+var pnpmV5KeyRe = regexp.MustCompile(`^((?:@[^/]+/)?[^/@]+)/(\d[^/]*)$`)
+
+// parsePnpmLockDeps reads the package keys of pnpm-lock.yaml, which look like
+// /name@1.0.0 (v6), name@1.0.0 (v9) or /name/1.0.0 (v5), possibly followed by
+// peer dependency suffixes.
+func parsePnpmLockDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	inPackages := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if line[0] != ' ' {
+			inPackages = line == "packages:"
+			continue
+		}
+		if !inPackages || strings.HasPrefix(line, "   ") || !strings.HasSuffix(line, ":") {
+			continue
+		}
+
+		key := strings.Trim(strings.TrimSuffix(strings.TrimSpace(line), ":"), "'\"")
+		key, _, _ = strings.Cut(key, "(")
+		key = strings.TrimPrefix(key, "/")
+		if m := pnpmV5KeyRe.FindStringSubmatch(key); m != nil {
+			version, _, _ := strings.Cut(m[2], "_") // Peer dependency suffix
+			addLockedVersion(deps, m[1], version)
+			continue
+		}
+		if name := npmSpecName(key); name != "" {
+			addLockedVersion(deps, name, key[len(name)+1:])
+		}
+	}
+	return deps, nil
+}
+
+var requirementRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*(.*)$`)
+
+// parseRequirementsDeps reads a pip requirements file. Options, includes and
+// bare URLs are skipped; names are normalized as pip does.
+func parseRequirementsDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	content = strings.ReplaceAll(content, "\\\n", " ")
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripYAMLComment(strings.TrimRight(line, "\r")))
+		if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") && !strings.Contains(line, " @ ") {
+			continue
+		}
+		m := requirementRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		spec, _, _ := strings.Cut(m[2], ";") // Environment markers
+		spec = strings.TrimSpace(spec)
+		if strings.HasPrefix(spec, "==") && !strings.ContainsAny(spec[2:], ",*") {
+			spec = strings.TrimSpace(strings.TrimLeft(spec, "="))
+		}
+		deps[normalizePythonName(m[1])] = depEntry{version: spec, direct: true}
+	}
+	return deps, nil
+}
+
+var pythonNameSepRe = regexp.MustCompile(`[-_.]+`)
+
+func normalizePythonName(name string) string {
+	return strings.ToLower(pythonNameSepRe.ReplaceAllString(name, "-"))
+}
+
+// parseTOMLPackageDeps reads the [[package]] tables of Cargo.lock and poetry.lock.
+func parseTOMLPackageDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	name, version, inPackage := "", "", false
+	flush := func() {
+		if inPackage {
+			addLockedVersion(deps, name, version)
+		}
+		name, version = "", ""
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			flush()
+			inPackage = line == "[[package]]"
+			continue
+		}
+		if !inPackage {
+			continue
+		}
+		key, value, ok := parseTOMLKeyValue(line)
+		switch {
+		case !ok:
+		case key == "name":
+			name = value
+		case key == "version":
+			version = value
+		}
+	}
+	flush()
+	return deps, nil
+}
+
+// parseCargoTomlDeps reads the dependency tables of a Cargo.toml, including
+// target-specific ones, [dependencies.name] tables and inline tables.
+func parseCargoTomlDeps(content string) (map[string]depEntry, error) {
+	deps := map[string]depEntry{}
+	set := func(name, version string, dev bool) {
+		entry, ok := deps[name]
+		if ok && !entry.dev && dev {
+			return // A normal dependency wins over the same dev-dependency
+		}
+		if version == "" {
+			version = entry.version
+		}
+		deps[name] = depEntry{version: version, direct: true, dev: dev}
+	}
+
+	table, tableDep, dev := "", "", false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripYAMLComment(strings.TrimRight(line, "\r")))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section := strings.Trim(line, "[] ")
+			parts := strings.Split(section, ".")
+			table, tableDep = "", ""
+			for i, part := range parts {
+				if isCargoDepTable(part) {
+					table = part
+					if i+1 < len(parts) {
+						tableDep = strings.Trim(strings.Join(parts[i+1:], "."), "\"'")
+					}
+				}
+			}
+			dev = table == "dev-dependencies"
+			if tableDep != "" && table != "" {
+				set(tableDep, "", dev)
+			}
+			continue
+		}
+		if table == "" {
+			continue
+		}
+
+		key, value, ok := parseTOMLKeyValue(line)
+		if !ok {
+			continue
+		}
+		if tableDep != "" {
+			if key == "version" {
+				set(tableDep, value, dev)
+			}
+			continue
+		}
+		name, sub, dotted := strings.Cut(key, ".")
+		switch {
+		case dotted && sub == "version":
+			set(name, value, dev)
+		case dotted:
+			set(name, "", dev)
+		case strings.HasPrefix(value, "{"):
+			version := ""
+			if m := cargoInlineVersionRe.FindStringSubmatch(value); m != nil {
+				version = m[1]
+			}
+			set(name, version, dev)
+		default:
+			set(name, value, dev)
+		}
+	}
+	return deps, nil
+}
+
+var cargoInlineVersionRe = regexp.MustCompile(`\bversion\s*=\s*"([^"]*)"`)
+
+func isCargoDepTable(name string) bool {
+	return name == "dependencies" || name == "dev-dependencies" || name == "build-dependencies"
+}
+
+// parseTOMLKeyValue splits `key = "value"`, unquoting the key and a string
+// value. Other values, such as inline tables, are returned as written.
+func parseTOMLKeyValue(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+	key = strings.Trim(strings.TrimSpace(key), "\"'")
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, key != ""
+}
+
+// dependencyRisk scores the dependency changes of a manifest or lockfile.
+func dependencyRisk(file *DiffFile) (int, []string) {
+	var added, major, downgraded []string
+	format, _ := dependencyFormat(file.Path)
+	locked := 0
+	for _, c := range file.Dependencies {
+		if !c.Direct || format.lockfile {
+			locked++
+			if c.Major {
+				major = append(major, describeVersionChange(c))
+			}
+			continue
+		}
+		switch {
+		case c.Change == "added":
+			added = append(added, c.Name)
+		case c.Change == "downgraded":
+			downgraded = append(downgraded, describeVersionChange(c))
+		}
+		if c.Major {
+			major = append(major, describeVersionChange(c))
+		}
+	}
+
+	score := 0
+	var reasons []string
+	if len(added) > 0 {
+		score += 10
+		reasons = append(reasons, "Adds direct dependencies: "+listReasonItems(added))
+	}
+	if len(major) > 0 {
+		score += 15
+		reasons = append(reasons, "Major version changes: "+listReasonItems(major))
+	}
+	if len(downgraded) > 0 {
+		score += 10
+		reasons = append(reasons, "Downgrades dependencies: "+listReasonItems(downgraded))
+	}
+	if locked > 0 && len(added)+len(downgraded) == 0 {
+		reasons = append(reasons, fmt.Sprintf("Changes %d locked or indirect dependencies", locked))
+	}
+	return score, reasons
+}
+
+// describeVersionChange renders a change as "name old → new".
+func describeVersionChange(c DependencyChange) string {
+	if c.OldName != "" {
+		return fmt.Sprintf("%s %s → %s %s", c.OldName, c.OldVersion, c.Name, c.NewVersion)
+	}
+	return fmt.Sprintf("%s %s → %s", c.Name, c.OldVersion, c.NewVersion)
+}
+
+// summarizeDependencies collects the dependency changes of all files.
+func summarizeDependencies(data *DiffData) DependencySummary {
+	summary := DependencySummary{Changes: []DependencyChange{}, Lockfiles: []string{}}
+	if data == nil {
+		return summary
+	}
+	for _, f := range data.Files {
+		if format, ok := dependencyFormat(f.Path); ok && format.lockfile && f.Dependencies != nil {
+			summary.Lockfiles = append(summary.Lockfiles, f.Path)
+		}
+		for _, c := range f.Dependencies {
+			summary.Changes = append(summary.Changes, c)
+			switch c.Change {
+			case "added":
+				summary.Added++
+				if c.Direct {
+					summary.NewDirect++
+				}
+			case "removed":
+				summary.Removed++
+			case "upgraded":
+				summary.Upgraded++
+			case "downgraded":
+				summary.Downgraded++
+			}
+			if c.Major {
+				summary.MajorBumps++
+			}
+		}
+	}
+	return summary
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDependencyFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    map[string]depEntry
+	}{
+		{
+			name: "go.mod",
+			path: "go.mod",
+			content: `module example.com/app
+
+go 1.22
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	golang.org/x/mod v0.17.0
+	github.com/davecgh/go-spew v1.1.1 // indirect
+)
+`,
+			want: map[string]depEntry{
+				"github.com/pkg/errors":      {version: "v0.9.1", direct: true},
+				"golang.org/x/mod":           {version: "v0.17.0", direct: true},
+				"github.com/davecgh/go-spew": {version: "v1.1.1"},
+			},
+		},
+		{
+			name: "go.sum",
+			path: "go.sum",
+			content: `github.com/pkg/errors v0.9.1 h1:abc=
+github.com/pkg/errors v0.9.1/go.mod h1:def=
+golang.org/x/mod v0.16.0 h1:ghi=
+golang.org/x/mod v0.17.0 h1:jkl=
+golang.org/x/mod v0.18.0/go.mod h1:mno=
+`,
+			want: map[string]depEntry{
+				"github.com/pkg/errors": {version: "v0.9.1"},
+				"golang.org/x/mod":      {version: "v0.16.0, v0.17.0"},
+			},
+		},
+		{
+			name: "package.json",
+			path: "web/package.json",
+			content: `{
+  "name": "app",
+  "dependencies": {"react": "^18.2.0", "typescript": "^5.4.0"},
+  "peerDependencies": {"react-dom": ">=18"},
+  "devDependencies": {"jest": "^29.0.0", "typescript": "^5.4.0"}
+}`,
+			want: map[string]depEntry{
+				"react":      {version: "^18.2.0", direct: true},
+				"typescript": {version: "^5.4.0", direct: true},
+				"react-dom":  {version: ">=18", direct: true},
+				"jest":       {version: "^29.0.0", direct: true, dev: true},
+			},
+		},
+		{
+			name: "package-lock.json v1",
+			path: "package-lock.json",
+			content: `{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "react": {"version": "18.2.0"},
+    "legacy": {"version": "1.0.0", "dependencies": {"react": {"version": "17.0.2"}}}
+  }
+}`,
+			want: map[string]depEntry{
+				"react":  {version: "17.0.2, 18.2.0"},
+				"legacy": {version: "1.0.0"},
+			},
+		},
+		{
+			name: "package-lock.json v3",
+			path: "package-lock.json",
+			content: `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app", "version": "1.0.0"},
+    "node_modules/react": {"version": "18.2.0"},
+    "node_modules/legacy/node_modules/react": {"version": "17.0.2"},
+    "node_modules/@babel/core": {"version": "7.24.0"}
+  }
+}`,
+			want: map[string]depEntry{
+				"react":       {version: "17.0.2, 18.2.0"},
+				"@babel/core": {version: "7.24.0"},
+			},
+		},
+		{
+			name: "yarn.lock classic",
+			path: "yarn.lock",
+			content: `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/core@^7.0.0", "@babel/core@^7.24.0":
+  version "7.24.0"
+  resolved "https://registry.yarnpkg.com/@babel/core/-/core-7.24.0.tgz"
+
+lodash@^4.17.21:
+  version "4.17.21"
+`,
+			want: map[string]depEntry{
+				"@babel/core": {version: "7.24.0"},
+				"lodash":      {version: "4.17.21"},
+			},
+		},
+		{
+			name: "yarn.lock Berry",
+			path: "yarn.lock",
+			content: `__metadata:
+  version: 6
+  cacheKey: 8
+
+"@scope/pkg@npm:1.0.0, @scope/pkg@npm:^1.0.0":
+  version: 1.0.0
+  resolution: "@scope/pkg@npm:1.0.0"
+
+"lodash@npm:^4.17.21":
+  version: 4.17.21
+  resolution: "lodash@npm:4.17.21"
+`,
+			want: map[string]depEntry{
+				"@scope/pkg": {version: "1.0.0"},
+				"lodash":     {version: "4.17.21"},
+			},
+		},
+		{
+			name: "pnpm-lock.yaml v5",
+			path: "pnpm-lock.yaml",
+			content: `lockfileVersion: 5.4
+
+specifiers:
+  react: ^18.2.0
+
+packages:
+
+  /react/18.2.0:
+    resolution: {integrity: sha512-abc}
+    dependencies:
+      loose-envify: 1.4.0
+    dev: false
+
+  /@types/react/18.2.0_react@18.2.0:
+    resolution: {integrity: sha512-def}
+`,
+			want: map[string]depEntry{
+				"react":        {version: "18.2.0"},
+				"@types/react": {version: "18.2.0"},
+			},
+		},
+		{
+			name: "pnpm-lock.yaml v6",
+			path: "pnpm-lock.yaml",
+			content: `lockfileVersion: '6.0'
+
+dependencies:
+  react:
+    specifier: ^18.2.0
+    version: 18.2.0
+
+packages:
+
+  /react@18.2.0:
+    resolution: {integrity: sha512-abc}
+
+  /@types/react@18.2.0(react@18.2.0):
+    resolution: {integrity: sha512-def}
+`,
+			want: map[string]depEntry{
+				"react":        {version: "18.2.0"},
+				"@types/react": {version: "18.2.0"},
+			},
+		},
+		{
+			name: "pnpm-lock.yaml v9",
+			path: "pnpm-lock.yaml",
+			content: `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      react:
+        specifier: ^18.2.0
+        version: 18.2.0
+
+packages:
+
+  react@18.2.0:
+    resolution: {integrity: sha512-abc}
+
+  '@types/react@18.2.0':
+    resolution: {integrity: sha512-def}
+
+snapshots:
+
+  react@18.2.0: {}
+`,
+			want: map[string]depEntry{
+				"react":        {version: "18.2.0"},
+				"@types/react": {version: "18.2.0"},
+			},
+		},
+		{
+			name: "requirements.txt",
+			path: "requirements-dev.txt",
+			content: `# Pinned for the API
+-r base.txt
+Django==4.2.1
+requests>=2.31,<3
+Flask_Login[extra] == 0.6.3 ; python_version >= "3.8"
+https://example.com/vendored.tar.gz
+`,
+			want: map[string]depEntry{
+				"django":      {version: "4.2.1", direct: true},
+				"requests":    {version: ">=2.31,<3", direct: true},
+				"flask-login": {version: "0.6.3", direct: true},
+			},
+		},
+		{
+			name: "Cargo.toml",
+			path: "Cargo.toml",
+			content: `[package]
+name = "app"
+version = "0.1.0"
+
+[dependencies]
+serde = "1.0"
+tokio = { version = "1.37", features = ["full"] }
+local = { path = "../local" }
+rand.version = "0.8"
+
+[dependencies.regex]
+version = "1.10"
+default-features = false
+
+[dev-dependencies]
+criterion = "0.5"
+serde = "1.0"
+
+[target.'cfg(unix)'.dependencies]
+libc = "0.2" # For the signal handler
+`,
+			want: map[string]depEntry{
+				"serde":     {version: "1.0", direct: true},
+				"tokio":     {version: "1.37", direct: true},
+				"local":     {direct: true},
+				"rand":      {version: "0.8", direct: true},
+				"regex":     {version: "1.10", direct: true},
+				"criterion": {version: "0.5", direct: true, dev: true},
+				"libc":      {version: "0.2", direct: true},
+			},
+		},
+		{
+			name: "Cargo.lock",
+			path: "Cargo.lock",
+			content: `version = 3
+
+[[package]]
+name = "serde"
+version = "1.0.200"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "syn"
+version = "1.0.109"
+
+[[package]]
+name = "syn"
+version = "2.0.60"
+`,
+			want: map[string]depEntry{
+				"serde": {version: "1.0.200"},
+				"syn":   {version: "1.0.109, 2.0.60"},
+			},
+		},
+		{
+			name: "poetry.lock",
+			path: "poetry.lock",
+			content: `[[package]]
+name = "requests"
+version = "2.31.0"
+description = "Python HTTP for Humans."
+
+[package.dependencies]
+certifi = ">=2017.4.17"
+
+[[package]]
+name = "certifi"
+version = "2024.2.2"
+
+[metadata]
+lock-version = "2.0"
+`,
+			want: map[string]depEntry{
+				"requests": {version: "2.31.0"},
+				"certifi":  {version: "2024.2.2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ok := dependencyFormat(tt.path)
+			if !ok {
+				t.Fatalf("no dependency format for %s", tt.path)
+			}
+			got, err := format.parse(tt.content)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffDependencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		ecosystem string
+		before    map[string]depEntry
+		after     map[string]depEntry
+		want      []DependencyChange
+	}{
+		{
+			name:      "go major version path",
+			ecosystem: "go",
+			before:    map[string]depEntry{"example.com/mod": {version: "v1.5.0", direct: true}},
+			after:     map[string]depEntry{"example.com/mod/v2": {version: "v2.1.0", direct: true}},
+			want: []DependencyChange{
+				{Name: "example.com/mod/v2", OldName: "example.com/mod", Change: "upgraded", OldVersion: "v1.5.0", NewVersion: "v2.1.0", Direct: true, Major: true},
+			},
+		},
+		{
+			name:      "gopkg.in major version",
+			ecosystem: "go",
+			before:    map[string]depEntry{"gopkg.in/yaml.v2": {version: "v2.4.0", direct: true}},
+			after:     map[string]depEntry{"gopkg.in/yaml.v3": {version: "v3.0.1", direct: true}},
+			want: []DependencyChange{
+				{Name: "gopkg.in/yaml.v3", OldName: "gopkg.in/yaml.v2", Change: "upgraded", OldVersion: "v2.4.0", NewVersion: "v3.0.1", Direct: true, Major: true},
+			},
+		},
+		{
+			name:      "go major version path back down",
+			ecosystem: "go",
+			before:    map[string]depEntry{"example.com/mod/v3": {version: "v3.0.0"}},
+			after:     map[string]depEntry{"example.com/mod/v2": {version: "v2.9.0"}},
+			want: []DependencyChange{
+				{Name: "example.com/mod/v2", OldName: "example.com/mod/v3", Change: "downgraded", OldVersion: "v3.0.0", NewVersion: "v2.9.0", Major: true},
+			},
+		},
+		{
+			name:      "unrelated go modules stay apart",
+			ecosystem: "go",
+			before:    map[string]depEntry{"example.com/a": {version: "v1.0.0"}},
+			after:     map[string]depEntry{"example.com/b/v2": {version: "v2.0.0"}},
+			want: []DependencyChange{
+				{Name: "example.com/a", Change: "removed", OldVersion: "v1.0.0"},
+				{Name: "example.com/b/v2", Change: "added", NewVersion: "v2.0.0"},
+			},
+		},
+		{
+			name:      "minor bump below 1.0 is major",
+			ecosystem: "npm",
+			before:    map[string]depEntry{"a": {version: "^0.3.1"}, "b": {version: "0.3.1"}, "c": {version: "1.2.0"}},
+			after:     map[string]depEntry{"a": {version: "^0.4.0"}, "b": {version: "0.3.2"}, "c": {version: "1.9.0"}},
+			want: []DependencyChange{
+				{Name: "a", Change: "upgraded", OldVersion: "^0.3.1", NewVersion: "^0.4.0", Major: true},
+				{Name: "b", Change: "upgraded", OldVersion: "0.3.1", NewVersion: "0.3.2"},
+				{Name: "c", Change: "upgraded", OldVersion: "1.2.0", NewVersion: "1.9.0"},
+			},
+		},
+		{
+			name:      "several locked versions",
+			ecosystem: "npm",
+			before:    map[string]depEntry{"react": {version: "17.0.2, 18.2.0"}},
+			after:     map[string]depEntry{"react": {version: "18.2.0"}},
+			want: []DependencyChange{
+				{Name: "react", Change: "changed", OldVersion: "17.0.2, 18.2.0", NewVersion: "18.2.0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.want {
+				tt.want[i].Ecosystem, tt.want[i].Manifest = tt.ecosystem, "deps"
+			}
+			got := diffDependencies(tt.before, tt.after, tt.ecosystem, "deps")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMajorVersionChange(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     bool
+	}{
+		{"1.4.0", "2.0.0", true},
+		{"v1.9.9", "v1.10.0", false},
+		{"0.3.1", "0.4.0", true},
+		{"~0.3.1", "~0.3.9", false},
+		{"0.0.1", "0.0.2", false},
+		{"1.0.0, 2.0.0", "2.0.0", false},
+		{"*", "2.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := isMajorVersionChange(tt.old, tt.new); got != tt.want {
+			t.Errorf("isMajorVersionChange(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		full := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetectDependencyChangesFiltersLockfiles(t *testing.T) {
+	repo := t.TempDir()
+	lock := func(versions string) string {
+		return `{"lockfileVersion": 3, "packages": {"": {}, ` + versions + `}}`
+	}
+	writeTestFiles(t, repo, map[string]string{
+		"package.json":          `{"dependencies": {"react": "^17.0.0"}}`,
+		"package-lock.json":     lock(`"node_modules/react": {"version": "17.0.2"}, "node_modules/loose-envify": {"version": "1.4.0"}`),
+		"web/package.json":      `{"dependencies": {"react": "^17.0.0"}}`,
+		"web/package-lock.json": lock(`"node_modules/react": {"version": "17.0.2"}`),
+	})
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "commit", "-qm", "base"},
+	} {
+		if _, err := runGit(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The root manifest changes with its lockfile; web/ only has its lockfile updated
+	writeTestFiles(t, repo, map[string]string{
+		"package.json":          `{"dependencies": {"react": "^18.2.0", "lodash": "^4.17.21"}}`,
+		"package-lock.json":     lock(`"node_modules/react": {"version": "18.2.0"}, "node_modules/loose-envify": {"version": "1.4.1"}, "node_modules/lodash": {"version": "4.17.21"}`),
+		"web/package-lock.json": lock(`"node_modules/react": {"version": "17.0.3"}`),
+	})
+
+	files := []*DiffFile{
+		{Path: "package.json", Status: "modified"},
+		{Path: "package-lock.json", Status: "modified"},
+		{Path: "web/package-lock.json", Status: "modified"},
+	}
+	detectDependencyChanges(repo, fileSource{Kind: "ref", Ref: "HEAD"}, fileSource{Kind: "worktree"}, files)
+
+	type change struct {
+		name, change string
+		direct       bool
+	}
+	want := map[string][]change{
+		"package.json":          {{"lodash", "added", true}, {"react", "upgraded", true}},
+		"package-lock.json":     {{"loose-envify", "upgraded", false}},
+		"web/package-lock.json": {{"react", "upgraded", true}},
+	}
+	for _, file := range files {
+		var got []change
+		for _, c := range file.Dependencies {
+			got = append(got, change{c.Name, c.Change, c.Direct})
+		}
+		if !reflect.DeepEqual(got, want[file.Path]) {
+			t.Errorf("%s: got %+v, want %+v", file.Path, got, want[file.Path])
+		}
+		if lockfile := file.Path != "package.json"; file.Collapsed != lockfile {
+			t.Errorf("%s: collapsed = %v, want %v", file.Path, file.Collapsed, lockfile)
+		}
+	}
+}
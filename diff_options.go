@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOptions controls how git generates the diff under review.
+type DiffOptions struct {
+	ContextLines     int    `json:"contextLines"`               // Lines of unified context (-U)
+	IgnoreWhitespace string `json:"ignoreWhitespace,omitempty"` // "", "all" (-w), "change" (-b), "eol" (--ignore-space-at-eol)
+	IgnoreBlankLines bool   `json:"ignoreBlankLines,omitempty"`
+	RenameThreshold  int    `json:"renameThreshold,omitempty"` // Similarity percent for rename detection, 0 = git default
+	CopyThreshold    int    `json:"copyThreshold,omitempty"`   // Similarity percent for copy detection, 0 = git default
+	Algorithm        string `json:"algorithm,omitempty"`       // "", "myers", "minimal", "patience", "histogram"
+
+	Include []string `json:"include,omitempty"` // Pathspec globs to limit the diff to
+	Exclude []string `json:"exclude,omitempty"` // Pathspec globs to leave out of the diff
+
+	// Files over these limits are listed as too large instead of being loaded
+	MaxFileBytes  int `json:"maxFileBytes"`
+	MaxFileLines  int `json:"maxFileLines"`
+	MaxTotalBytes int `json:"maxTotalBytes"` // Patch text budget for the whole diff
+}
+
+const defaultContextLines = 3
+
+func validateDiffOptions(opts DiffOptions) error {
+	if opts.ContextLines < 0 || opts.ContextLines > 10000 {
+		return fmt.Errorf("context lines must be between 0 and 10000")
+	}
+	switch opts.IgnoreWhitespace {
+	case "", "none", "all", "change", "eol":
+	default:
+		return fmt.Errorf("invalid ignore-whitespace mode %q (use none, all, change or eol)", opts.IgnoreWhitespace)
+	}
+	if opts.RenameThreshold < 0 || opts.RenameThreshold > 100 {
+		return fmt.Errorf("rename threshold must be between 0 and 100")
+	}
+	if opts.CopyThreshold < 0 || opts.CopyThreshold > 100 {
+		return fmt.Errorf("copy threshold must be between 0 and 100")
+	}
+	switch opts.Algorithm {
+	case "", "myers", "minimal", "patience", "histogram":
+	default:
+		return fmt.Errorf("invalid diff algorithm %q (use myers, minimal, patience or histogram)", opts.Algorithm)
+	}
+	if opts.MaxFileBytes < 0 || opts.MaxFileLines < 0 || opts.MaxTotalBytes < 0 {
+		return fmt.Errorf("size limits must not be negative")
+	}
+	for _, p := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if strings.HasPrefix(strings.TrimSpace(p), ":") {
+			return fmt.Errorf("pathspec magic is not supported in %q; use plain globs", p)
+		}
+	}
+	return nil
+}
+
+// normalizeDiffOptions trims and lowercases string options and fills in default size limits.
+func normalizeDiffOptions(opts DiffOptions) DiffOptions {
+	opts.IgnoreWhitespace = strings.ToLower(strings.TrimSpace(opts.IgnoreWhitespace))
+	if opts.IgnoreWhitespace == "none" {
+		opts.IgnoreWhitespace = ""
+	}
+	opts.Algorithm = strings.ToLower(strings.TrimSpace(opts.Algorithm))
+	if opts.MaxFileBytes == 0 {
+		opts.MaxFileBytes = defaultMaxFileBytes
+	}
+	if opts.MaxFileLines == 0 {
+		opts.MaxFileLines = defaultMaxFileLines
+	}
+	if opts.MaxTotalBytes == 0 {
+		opts.MaxTotalBytes = defaultMaxTotalBytes
+	}
+	return opts
+}
+
+// limits returns the size limits for streaming the diff, using the defaults for unset values.
+func (opts DiffOptions) limits() diffLimits {
+	opts = normalizeDiffOptions(opts)
+	return diffLimits{
+		FileBytes:  opts.MaxFileBytes,
+		FileLines:  opts.MaxFileLines,
+		TotalBytes: opts.MaxTotalBytes,
+	}
+}
+
+// gitArgs translates the options into git diff flags.
+func (opts DiffOptions) gitArgs() []string {
+	args := []string{fmt.Sprintf("-U%d", opts.ContextLines)}
+
+	switch opts.IgnoreWhitespace {
+	case "all":
+		args = append(args, "--ignore-all-space")
+	case "change":
+		args = append(args, "--ignore-space-change")
+	case "eol":
+		args = append(args, "--ignore-space-at-eol")
+	}
+	if opts.IgnoreBlankLines {
+		args = append(args, "--ignore-blank-lines")
+	}
+
+	if opts.RenameThreshold > 0 {
+		args = append(args, fmt.Sprintf("--find-renames=%d%%", opts.RenameThreshold))
+	} else {
+		args = append(args, "--find-renames")
+	}
+	if opts.CopyThreshold > 0 {
+		args = append(args, fmt.Sprintf("--find-copies=%d%%", opts.CopyThreshold))
+	} else {
+		args = append(args, "--find-copies")
+	}
+
+	if opts.Algorithm != "" {
+		args = append(args, "--diff-algorithm="+opts.Algorithm)
+	}
+
+	return args
+}
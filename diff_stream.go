@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Default size limits for a single diff load. Files over a per-file limit, and
+// every file after the total budget is spent, are listed as "too large" with
+// line counts but no hunks.
+const (
+	defaultMaxFileBytes  = 2 * 1024 * 1024
+	defaultMaxFileLines  = 20000
+	defaultMaxTotalBytes = 64 * 1024 * 1024
+)
+
+// diffLimits caps how much patch text is kept in memory. Zero means unlimited.
+type diffLimits struct {
+	FileBytes  int
+	FileLines  int
+	TotalBytes int
+}
+
+// diffAssembler turns patch sections into DiffFiles and matches each one to its
+// `git diff --raw -z` entry, which is authoritative for paths and status.
+// Entries without a patch section are still listed, with no hunks.
+type diffAssembler struct {
+	entries    []rawDiffEntry
+	byHeader   map[string]int
+	matched    []*DiffFile
+	files      []*DiffFile
+	submodules strings.Builder // "Submodule ..." log lines, parsed in finish
+}
+
+func newDiffAssembler() *diffAssembler {
+	return &diffAssembler{byHeader: map[string]int{}}
+}
+
+func (a *diffAssembler) addEntry(entry rawDiffEntry) {
+	a.byHeader[entry.headerKey()] = len(a.entries)
+	a.entries = append(a.entries, entry)
+	a.matched = append(a.matched, nil)
+}
+
+// addFile records a parsed section. header is the diff --git line without its prefix.
+func (a *diffAssembler) addFile(header string, file *DiffFile) {
+	if _, _, key, _ := decodeDiffHeaderPaths(header); key != "" {
+		if idx, ok := a.byHeader[key]; ok {
+			if a.matched[idx] == nil {
+				a.matched[idx] = file
+				applyRawDiffEntry(file, a.entries[idx])
+			} else if a.entries[idx].Status == "T" {
+				// Type changes are emitted as a deletion followed by an addition.
+				mergeFileDiff(a.matched[idx], file)
+				return
+			}
+		}
+	}
+	a.files = append(a.files, file)
+}
+
+// finish lists unmatched entries and derives mode-dependent statuses.
+func (a *diffAssembler) finish() []*DiffFile {
+	files := a.files
+	for i, entry := range a.entries {
+		if a.matched[i] != nil {
+			continue
+		}
+		file := &DiffFile{
+			RiskReasons: []string{},
+			Hunks:       []*DiffHunk{},
+		}
+		applyRawDiffEntry(file, entry)
+		files = append(files, file)
+	}
+
+	_, submodules := extractSubmoduleLogs(a.submodules.String())
+	for _, file := range files {
+		refineFileStatus(file)
+		if sub, ok := submodules[file.Path]; ok {
+			file.Submodule = sub
+			file.Status = "submodule"
+		}
+	}
+
+	return files
+}
+
+// diffStream reads `git diff --patch-with-raw -z` output one file section at a
+// time, so neither the whole patch nor an oversized file is held in memory.
+type diffStream struct {
+	asm         *diffAssembler
+	limits      diffLimits
+	totalBytes  int
+	budgetSpent bool // TotalBytes was reached; every later file is too large
+}
+
+func newDiffStream(limits diffLimits) *diffStream {
+	return &diffStream{asm: newDiffAssembler(), limits: limits}
+}
+
+// run executes git with args and extra environment and parses its stdout as
+// it is produced.
+func (s *diffStream) run(repoPath string, env []string, args []string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+
+	readErr := s.read(stdout)
+	if readErr != nil {
+		// Drain the pipe so git can exit
+		io.Copy(io.Discard, stdout)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// git diff returns exit code 1 when differences are found.
+		if ee, ok := err.(*exec.ExitError); !ok || ee.ExitCode() != 1 {
+			return fmt.Errorf("git %s failed: %w\nstderr: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if readErr != nil {
+		return fmt.Errorf("failed to read git diff output: %w", readErr)
+	}
+	return nil
+}
+
+// read parses the raw records and the patch that follows them from r.
+func (s *diffStream) read(r io.Reader) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	// Raw records: ":<modes> <shas> <status>\0<path>\0[<path>\0]"
+	for {
+		next, err := br.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if next[0] != ':' {
+			break
+		}
+
+		meta, err := br.ReadString(0)
+		if err != nil {
+			return err
+		}
+		entry, ok := parseRawDiffMeta(strings.TrimSuffix(meta[1:], "\x00"))
+		if !ok {
+			break
+		}
+		var paths []string
+		for i := 0; i < entry.pathCount(); i++ {
+			path, err := br.ReadString(0)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			paths = append(paths, strings.TrimSuffix(path, "\x00"))
+		}
+		if len(paths) == 2 {
+			entry.OldPath, entry.Path = paths[0], paths[1]
+		} else {
+			entry.Path = paths[0]
+		}
+		s.asm.addEntry(entry)
+	}
+
+	// The raw section is terminated by an extra NUL before the patch.
+	if next, err := br.Peek(1); err == nil && next[0] == 0 {
+		br.ReadByte()
+	}
+
+	var section *diffSection
+	inSubmoduleLog := false
+	for {
+		line, truncated, err := s.readLine(br)
+		if err != io.EOF || line != "" || truncated {
+			switch {
+			case strings.HasPrefix(line, "diff --git "):
+				s.flush(section)
+				section = &diffSection{header: []string{line}}
+				inSubmoduleLog = false
+			case strings.HasPrefix(line, "Submodule "):
+				inSubmoduleLog = true
+				s.asm.submodules.WriteString(line + "\n")
+			case inSubmoduleLog && (strings.HasPrefix(line, "  > ") || strings.HasPrefix(line, "  < ")):
+				s.asm.submodules.WriteString(line + "\n")
+			default:
+				inSubmoduleLog = false
+				if section != nil {
+					s.addLine(section, line, truncated)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	s.flush(section)
+
+	return nil
+}
+
+// readLine reads one patch line without its newline. Lines longer than the
+// per-file byte limit are cut short and reported as truncated.
+func (s *diffStream) readLine(br *bufio.Reader) (string, bool, error) {
+	var buf []byte
+	truncated := false
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if s.limits.FileBytes > 0 && len(buf)+len(chunk) > s.limits.FileBytes {
+			truncated = true
+		} else {
+			buf = append(buf, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return strings.TrimSuffix(string(buf), "\n"), truncated, err
+	}
+}
+
+// diffSection accumulates one file's patch text.
+type diffSection struct {
+	header   []string // diff --git line and metadata up to the first hunk
+	body     []string
+	inBody   bool
+	bytes    int
+	lines    int
+	added    int
+	removed  int
+	tooLarge bool
+}
+
+func (s *diffStream) addLine(sec *diffSection, line string, truncated bool) {
+	if !sec.inBody {
+		if !strings.HasPrefix(line, "@@") && !strings.HasPrefix(line, "Binary files") {
+			sec.header = append(sec.header, line)
+			return
+		}
+		sec.inBody = true
+		sec.tooLarge = s.budgetSpent
+	}
+
+	if strings.HasPrefix(line, "+") {
+		sec.added++
+	} else if strings.HasPrefix(line, "-") {
+		sec.removed++
+	}
+	if sec.tooLarge {
+		return
+	}
+
+	sec.bytes += len(line) + 1
+	sec.lines++
+	s.totalBytes += len(line) + 1
+	if s.limits.TotalBytes > 0 && s.totalBytes > s.limits.TotalBytes {
+		s.budgetSpent = true
+	}
+	if truncated || s.budgetSpent ||
+		(s.limits.FileBytes > 0 && sec.bytes > s.limits.FileBytes) ||
+		(s.limits.FileLines > 0 && sec.lines > s.limits.FileLines) {
+		sec.tooLarge = true
+		s.totalBytes -= sec.bytes
+		sec.body = nil
+		return
+	}
+	sec.body = append(sec.body, line)
+}
+
+// flush parses a completed section and hands it to the assembler.
+func (s *diffStream) flush(sec *diffSection) {
+	if sec == nil {
+		return
+	}
+
+	lines := append(sec.header, sec.body...)
+	file := parseFileDiff(strings.Join(lines, "\n") + "\n")
+	if file == nil {
+		return
+	}
+	if sec.tooLarge {
+		file.TooLarge = true
+		file.Hunks = []*DiffHunk{}
+		file.RawDiff = ""
+		file.LinesAdded = sec.added
+		file.LinesRemoved = sec.removed
+	}
+
+	s.asm.addFile(strings.TrimPrefix(sec.header[0], "diff --git "), file)
+}
+
+func (s *diffStream) finish() []*DiffFile {
+	return s.asm.finish()
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxFileContentBytes caps how much of a single file version is loaded for context expansion.
+const maxFileContentBytes = 5 * 1024 * 1024
+
+// fileSource identifies where one side of the diff reads file contents from.
+type fileSource struct {
+	Kind  string // ref, index, worktree
+	Ref   string // Commit-ish for Kind == "ref"
+	Stage int    // Merge stage for Kind == "index": 1 base, 2 ours, 3 theirs
+}
+
+// FileLine is a numbered line of a file version.
+type FileLine struct {
+	Number  int    `json:"number"`
+	Content string `json:"content"`
+}
+
+// FileLinesResult is a line range from one side of the diff.
+type FileLinesResult struct {
+	Path       string     `json:"path"`
+	Side       string     `json:"side"` // old, new
+	Start      int        `json:"start"`
+	End        int        `json:"end"`
+	TotalLines int        `json:"totalLines"`
+	Lines      []FileLine `json:"lines"`
+}
+
+// FullFileResult is the whole new version of a file with changes from the diff marked.
+type FullFileResult struct {
+	Path          string     `json:"path"`
+	OldPath       string     `json:"oldPath,omitempty"`
+	OldExists     bool       `json:"oldExists"`
+	NewExists     bool       `json:"newExists"`
+	OldTotalLines int        `json:"oldTotalLines"`
+	NewTotalLines int        `json:"newTotalLines"`
+	Lines         []DiffLine `json:"lines"`
+}
+
+// diffSources resolves where the old and new versions of files come from for the current diff mode.
+func diffSources(cfg *Config) (fileSource, fileSource, error) {
+	cmp, err := resolveComparison(cfg)
+	if err != nil {
+		return fileSource{}, fileSource{}, err
+	}
+	return cmp.Old, cmp.New, nil
+}
+
+// readFileVersion returns the contents of path from src. exists is false when
+// the file is not present on that side (added or deleted files).
+func readFileVersion(repoPath string, src fileSource, path string) (content string, exists bool, err error) {
+	switch src.Kind {
+	case "worktree":
+		fullPath, err := resolveRepoFilePath(repoPath, path)
+		if err != nil {
+			return "", false, err
+		}
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if info.Size() > maxFileContentBytes {
+			return "", true, fmt.Errorf("%s is too large to display (%d bytes)", path, info.Size())
+		}
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return checkTextContent(path, data)
+	case "index", "ref":
+		spec := gitBlobSpec(src, path)
+		if _, err := runGit(repoPath, "cat-file", "-e", spec); err != nil {
+			return "", false, nil
+		}
+		if sizeOut, err := runGit(repoPath, "cat-file", "-s", spec); err == nil {
+			if size, parseErr := strconv.ParseInt(strings.TrimSpace(sizeOut), 10, 64); parseErr == nil && size > maxFileContentBytes {
+				return "", true, fmt.Errorf("%s is too large to display (%d bytes)", path, size)
+			}
+		}
+		data, err := gitShowBlob(repoPath, spec)
+		if err != nil {
+			return "", false, err
+		}
+		return checkTextContent(path, data)
+	default:
+		return "", false, fmt.Errorf("unknown file source %q", src.Kind)
+	}
+}
+
+// readFileHead returns up to n bytes from the start of path in src, reading
+// no further and spawning at most one git process. A missing file reads as empty.
+func readFileHead(repoPath string, src fileSource, path string, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	switch src.Kind {
+	case "worktree":
+		fullPath, err := resolveRepoFilePath(repoPath, path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(fullPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer f.Close()
+		read, err := io.ReadFull(f, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return buf[:read], nil
+	case "index", "ref":
+		cmd := exec.Command("git", "cat-file", "blob", gitBlobSpec(src, path))
+		cmd.Dir = repoPath
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("git cat-file failed: %w", err)
+		}
+		read, _ := io.ReadFull(stdout, buf)
+		// Stop git instead of draining the rest of a large blob
+		cmd.Process.Kill()
+		cmd.Wait()
+		return buf[:read], nil
+	default:
+		return nil, fmt.Errorf("unknown file source %q", src.Kind)
+	}
+}
+
+// gitBlobSpec names path in an index or ref source for git cat-file and git show.
+func gitBlobSpec(src fileSource, path string) string {
+	if src.Kind == "ref" {
+		return src.Ref + ":" + path
+	}
+	if src.Stage > 0 {
+		return fmt.Sprintf(":%d:%s", src.Stage, path)
+	}
+	return ":" + path
+}
+
+// gitShowBlob runs `git show <spec>` and returns stdout only, so stderr
+// warnings never end up in file contents.
+func gitShowBlob(repoPath string, spec string) ([]byte, error) {
+	cmd := exec.Command("git", "show", "--no-textconv", spec)
+	cmd.Dir = repoPath
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show %s failed: %w\n%s", spec, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func checkTextContent(path string, data []byte) (string, bool, error) {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", true, fmt.Errorf("%s is a binary file", path)
+	}
+	return string(data), true, nil
+}
+
+// splitFileLines splits content into lines without a trailing empty element.
+func splitFileLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// GetFileLines returns lines [start, end] (1-based, inclusive) of the old or new version of a file.
+func GetFileLines(cfg *Config, path string, oldPath string, side string, start int, end int) (*FileLinesResult, error) {
+	oldSrc, newSrc, err := diffSources(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	src, readPath := newSrc, path
+	switch side {
+	case "old":
+		src = oldSrc
+		if oldPath != "" {
+			readPath = oldPath
+		}
+	case "new":
+	default:
+		return nil, fmt.Errorf("side must be old or new")
+	}
+
+	content, exists, err := readFileVersion(cfg.RepoPath, src, readPath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s does not exist on the %s side", readPath, side)
+	}
+
+	lines := splitFileLines(content)
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > len(lines) {
+		end = len(lines)
+	}
+
+	result := &FileLinesResult{
+		Path:       readPath,
+		Side:       side,
+		Start:      start,
+		End:        end,
+		TotalLines: len(lines),
+		Lines:      []FileLine{},
+	}
+	for n := start; n <= end; n++ {
+		result.Lines = append(result.Lines, FileLine{Number: n, Content: lines[n-1]})
+	}
+	return result, nil
+}
+
+// GetFullFile returns every line of the new version of a file, interleaved
+// with the deleted lines of the diff, each tagged with its kind and line numbers.
+func GetFullFile(cfg *Config, file *DiffFile) (*FullFileResult, error) {
+	oldSrc, newSrc, err := diffSources(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := file.Path
+	if file.OldPath != "" {
+		oldPath = file.OldPath
+	}
+
+	oldContent, oldExists, err := readFileVersion(cfg.RepoPath, oldSrc, oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newContent, newExists, err := readFileVersion(cfg.RepoPath, newSrc, file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	oldLines := splitFileLines(oldContent)
+	newLines := splitFileLines(newContent)
+
+	return &FullFileResult{
+		Path:          file.Path,
+		OldPath:       file.OldPath,
+		OldExists:     oldExists,
+		NewExists:     newExists,
+		OldTotalLines: len(oldLines),
+		NewTotalLines: len(newLines),
+		Lines:         buildFullFileLines(newLines, file.Hunks),
+	}, nil
+}
+
+// buildFullFileLines fills the gaps between hunks with unchanged lines from the
+// new file so the result covers the whole file.
+func buildFullFileLines(newLines []string, hunks []*DiffHunk) []DiffLine {
+	result := []DiffLine{}
+	oldNo, newNo := 1, 1
+
+	emitUnchanged := func(untilNew int) {
+		for newNo < untilNew && newNo <= len(newLines) {
+			result = append(result, DiffLine{Kind: "context", OldLine: oldNo, NewLine: newNo, Content: newLines[newNo-1]})
+			oldNo++
+			newNo++
+		}
+	}
+
+	for _, h := range hunks {
+		// A zero-length side starts *after* the given line number
+		hunkNewStart := h.NewStart
+		if h.NewLines == 0 {
+			hunkNewStart++
+		}
+		emitUnchanged(hunkNewStart)
+
+		result = append(result, h.Lines...)
+		oldNo = h.OldStart + h.OldLines
+		if h.OldLines == 0 {
+			oldNo++
+		}
+		newNo = hunkNewStart + h.NewLines
+	}
+	emitUnchanged(len(newLines) + 1)
+
+	return result
+}
@@ -37,6 +37,12 @@ const statusColors: Record<string, string> = {
   deleted: "bg-[#f8514920] text-[#f85149] border-[#f8514940]",
   renamed: "bg-[#bc8cff15] text-[#bc8cff] border-[#bc8cff30]",
   binary: "bg-[#8b949e20] text-[#8b949e] border-[#8b949e40]",
+  copied: "bg-[#bc8cff15] text-[#bc8cff] border-[#bc8cff30]",
+  modechange: "bg-[#d2992220] text-[#d29922] border-[#d2992240]",
+  symlink: "bg-[#d2992220] text-[#d29922] border-[#d2992240]",
+  submodule: "bg-[#d2992220] text-[#d29922] border-[#d2992240]",
+  typechange: "bg-[#d2992220] text-[#d29922] border-[#d2992240]",
+  conflicted: "bg-[#f8514920] text-[#f85149] border-[#f8514940]",
 }
 
 function riskBadgeClass(score: number) {
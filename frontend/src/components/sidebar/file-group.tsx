@@ -16,6 +16,7 @@ const groupLabels: Record<string, string> = {
   config: "Configuration",
   docs: "Documentation",
   style: "Styling",
+  generated: "Generated & Vendored",
 }
 
 const groupIcons: Record<string, string> = {
@@ -26,6 +27,7 @@ const groupIcons: Record<string, string> = {
   config: "\u2699\uFE0F",
   docs: "\uD83D\uDCDD",
   style: "\uD83C\uDFA8",
+  generated: "\uD83E\uDD16",
 }
 
 interface FileGroupProps {
@@ -14,6 +14,7 @@ const GROUP_ORDER = [
   "config",
   "docs",
   "style",
+  "generated",
 ]
 
 export function FileList() {
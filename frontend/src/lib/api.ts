@@ -3,18 +3,41 @@ import type {
   BranchesResponse,
   CommitPushRequest,
   CommitPushResponse,
+  CommitsResponse,
+  ConflictActionResponse,
+  ConflictFileDetail,
+  ConflictResolution,
+  ConflictResolveResponse,
+  ConflictState,
+  DependencySummary,
+  DiffFile,
   DiffResponse,
+  DiffSummaryResponse,
+  FileLinesResponse,
   FilePathRequest,
+  FixApplyRequest,
+  FixSuggestRequest,
+  FixSuggestion,
+  FullFileResponse,
   GitAIFileNotesResponse,
   GitAIPromptDetailResponse,
   GitHubPRCloseRequest,
   GitHubPROpenRequest,
   GitHubPROpenResponse,
   GitStatus,
+  HotHunk,
+  LoadCommitsRequest,
+  LoadPatchRequest,
+  RangeDiffResponse,
+  RangeDiffSpec,
   ReloadDiffRequest,
   RepoPickerResponse,
   ReposResponse,
+  RiskRuleSet,
   SelectRepoRequest,
+  StashActionRequest,
+  StashActionResponse,
+  StashListResponse,
 } from "@/types/api"
 
 async function readError(resp: Response, fallback: string): Promise<string> {
@@ -146,6 +169,182 @@ export async function closeGithubPr(payload: GitHubPRCloseRequest): Promise<{ ok
   return resp.json()
 }
 
+export async function fetchFileLines(params: {
+  path: string
+  oldPath?: string
+  side: "old" | "new"
+  start: number
+  end: number
+}): Promise<FileLinesResponse> {
+  const search = new URLSearchParams({
+    path: params.path,
+    side: params.side,
+    start: String(params.start),
+    end: String(params.end),
+  })
+  if (params.oldPath) {
+    search.set("oldPath", params.oldPath)
+  }
+
+  const resp = await fetch(`/api/file/lines?${search.toString()}`)
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch file lines: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchDiffSummary(): Promise<DiffSummaryResponse> {
+  const resp = await fetch("/api/diff/summary")
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch diff summary: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchDiffFile(path: string): Promise<DiffFile> {
+  const search = new URLSearchParams({ path })
+  const resp = await fetch(`/api/diff/file?${search.toString()}`)
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch file diff: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchCommits(): Promise<CommitsResponse> {
+  const resp = await fetch("/api/commits")
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch commits: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function loadCommits(payload: LoadCommitsRequest): Promise<DiffResponse> {
+  const resp = await fetch("/api/commits/load", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to load commits: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchRangeDiff(spec: RangeDiffSpec): Promise<RangeDiffResponse> {
+  const resp = await fetch("/api/rangediff", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(spec),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch range-diff: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function loadRangeDiff(spec: RangeDiffSpec): Promise<DiffResponse> {
+  const resp = await fetch("/api/rangediff/load", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(spec),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to load range-diff: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function loadPatch(payload: LoadPatchRequest): Promise<DiffResponse> {
+  const resp = await fetch("/api/patch/load", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to load patch: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function selectPatch(index?: number): Promise<DiffResponse> {
+  const resp = await fetch("/api/patch/select", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ index }),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to select patch: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchStashes(): Promise<StashListResponse> {
+  const resp = await fetch("/api/stash")
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch stashes: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function loadStash(ref: string): Promise<DiffResponse> {
+  const resp = await fetch("/api/stash/load", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ ref }),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to load stash: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function stashAction(
+  action: "apply" | "pop" | "drop",
+  payload: StashActionRequest,
+): Promise<StashActionResponse> {
+  const resp = await fetch(`/api/stash/${action}`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to ${action} stash: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchConflicts(): Promise<ConflictState> {
+  const resp = await fetch("/api/conflicts")
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch conflicts: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchConflictFile(path: string): Promise<ConflictFileDetail> {
+  const search = new URLSearchParams({ path })
+  const resp = await fetch(`/api/conflicts/file?${search.toString()}`)
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch conflicted file: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function resolveConflict(payload: ConflictResolution): Promise<ConflictResolveResponse> {
+  const resp = await fetch("/api/conflicts/resolve", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to resolve conflict: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function conflictAction(action: "continue" | "abort"): Promise<ConflictActionResponse> {
+  const resp = await fetch(`/api/conflicts/${action}`, { method: "POST" })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to ${action}: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchFullFile(path: string): Promise<FullFileResponse> {
+  const search = new URLSearchParams({ path })
+  const resp = await fetch(`/api/file/full?${search.toString()}`)
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch file: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function suggestFix(payload: FixSuggestRequest): Promise<FixSuggestion> {
+  const resp = await fetch("/api/ai/fix/suggest", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to suggest fix: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function applyFix(payload: FixApplyRequest): Promise<DiffResponse> {
+  const resp = await fetch("/api/ai/fix/apply", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(payload),
+  })
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to apply fix: ${resp.statusText}`))
+  return resp.json()
+}
+
 export async function fetchGitAIFileNotes(params: {
   path: string
   oldPath?: string
@@ -178,3 +377,24 @@ export async function fetchGitAIPromptDetail(params: {
   if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch Git AI prompt details: ${resp.statusText}`))
   return resp.json()
 }
+
+export async function fetchRiskRules(): Promise<RiskRuleSet> {
+  const resp = await fetch("/api/risk-rules")
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch risk rules: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchHottestHunks(limit?: number): Promise<HotHunk[]> {
+  const params = new URLSearchParams()
+  if (limit) params.set("limit", String(limit))
+  const query = params.toString()
+  const resp = await fetch(`/api/hunks/hottest${query ? `?${query}` : ""}`)
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch hottest hunks: ${resp.statusText}`))
+  return resp.json()
+}
+
+export async function fetchDependencies(): Promise<DependencySummary> {
+  const resp = await fetch("/api/dependencies")
+  if (!resp.ok) throw new Error(await readError(resp, `Failed to fetch dependencies: ${resp.statusText}`))
+  return resp.json()
+}
@@ -77,6 +77,8 @@ interface AppState {
     baseOid: string
     headOid: string
     mergeBaseOid: string
+    previousBaseOid?: string
+    previousHeadOid?: string
   }>
   closeGithubPr: () => Promise<void>
   nextFile: () => void
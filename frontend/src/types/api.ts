@@ -1,31 +1,164 @@
+export type DiffLineKind = "context" | "add" | "del"
+
+/** Changed region as UTF-16 offsets [start, end) into DiffLine.content, ready for slice() */
+export interface LineSpan {
+  start: number
+  end: number
+}
+
+export interface DiffLine {
+  kind: DiffLineKind
+  oldLine?: number
+  newLine?: number
+  content: string
+  noNewlineAtEof?: boolean
+  changes?: LineSpan[]
+}
+
 export interface DiffHunk {
   header: string
   content: string
   summary?: string
+  oldStart: number
+  oldLines: number
+  newStart: number
+  newLines: number
+  section?: string
+  lines: DiffLine[]
+  linesAdded: number
+  linesRemoved: number
+  riskScore: number
+  riskReasons?: string[]
+}
+
+export interface HotHunk {
+  path: string
+  hunkIndex: number
+  header: string
+  newStart: number
   linesAdded: number
   linesRemoved: number
+  riskScore: number
+  riskReasons: string[]
+  fileRiskScore: number
+}
+
+export type FileStatus =
+  | "added"
+  | "modified"
+  | "deleted"
+  | "renamed"
+  | "copied"
+  | "binary"
+  | "modechange"
+  | "symlink"
+  | "submodule"
+  | "typechange"
+  | "conflicted"
+
+export interface SubmoduleChange {
+  oldCommit?: string
+  newCommit?: string
+  commits: string[]
+  rewind?: boolean
+  note?: string
+}
+
+export interface LFSChange {
+  oldOid?: string
+  newOid?: string
+  oldSize?: number
+  newSize?: number
 }
 
 export interface DiffFile {
   path: string
   oldPath?: string
-  status: "added" | "modified" | "deleted" | "renamed" | "binary"
+  status: FileStatus
   language: string
   hunks: DiffHunk[]
   rawDiff: string
   linesAdded: number
   linesRemoved: number
+  oldMode?: string
+  newMode?: string
+  similarity?: number
+  submodule?: SubmoduleChange
+  lfs?: LFSChange
+  collapsed?: boolean
+  tooLarge?: boolean
+  generated?: boolean
+  generatedReason?: string
   riskScore: number
   riskReasons: string[]
+  riskEvidence?: RiskEvidence[]
+  secrets?: SecretFinding[]
+  apiChanges?: APIChange[]
+  dependencies?: DependencyChange[]
   semanticGroup: string
   summary?: string
   checklist?: string[]
 }
 
+export interface RiskEvidence {
+  rule: string
+  reason: string
+  match: "path" | "added" | "removed"
+  lines?: number[]
+  count?: number
+  snippet?: string
+}
+
+export interface APIChange {
+  change: "added" | "removed" | "changed"
+  kind: "func" | "method" | "type" | "field" | "interface-method" | "interface-embed"
+  name: string
+  old?: string
+  new?: string
+  breaking: boolean
+  line: number
+}
+
+export interface DependencyChange {
+  name: string
+  oldName?: string
+  ecosystem: "go" | "npm" | "pypi" | "cargo"
+  manifest: string
+  change: "added" | "removed" | "upgraded" | "downgraded" | "changed"
+  oldVersion?: string
+  newVersion?: string
+  direct: boolean
+  dev?: boolean
+  major?: boolean
+}
+
+export interface DependencySummary {
+  changes: DependencyChange[]
+  added: number
+  removed: number
+  upgraded: number
+  downgraded: number
+  majorBumps: number
+  newDirect: number
+  lockfiles: string[]
+}
+
+export interface SecretFinding {
+  kind: string
+  line: number
+  hunk: number
+  snippet: string
+}
+
 export interface DiffStats {
   totalFiles: number
   totalAdded: number
   totalRemoved: number
+  excludedFiles: number
+  ignoredFiles: number
+  collapsedFiles: number
+  tooLargeFiles: number
+  secretFiles: number
   groupCounts: Record<string, number>
   riskDistribution: {
     high: number
@@ -34,12 +167,38 @@ export interface DiffStats {
   }
 }
 
+export interface DiffOptions {
+  contextLines: number
+  ignoreWhitespace?: "" | "all" | "change" | "eol"
+  ignoreBlankLines?: boolean
+  renameThreshold?: number
+  copyThreshold?: number
+  algorithm?: "" | "myers" | "minimal" | "patience" | "histogram"
+  include?: string[]
+  exclude?: string[]
+  maxFileBytes?: number
+  maxFileLines?: number
+  maxTotalBytes?: number
+}
+
 export interface DiffResponse {
   baseRef: string
   headRef: string
+  mode?: CompareMode | ""
+  baseSha?: string
+  headSha?: string
+  mergeBase?: string
+  commits?: CommitRange | null
+  rangeDiff?: RangeDiffSpec | null
+  stash?: string
+  patch?: PatchSeries | null
+  notice?: string
+  riskRuleErrors?: string[]
+  diffOptions: DiffOptions
   files: DiffFile[]
   aiProvider: string
   stats: DiffStats
+  dependencies: DependencySummary
   gitStatus: GitStatus
   repos: Repo[]
   currentRepoId: string
@@ -47,10 +206,79 @@ export interface DiffResponse {
   aiError: string
 }
 
+export type DiffFileSummary = Omit<DiffFile, "hunks" | "rawDiff"> & {
+  hunkCount: number
+}
+
+export interface DiffSummaryResponse extends Omit<DiffResponse, "files"> {
+  files: DiffFileSummary[]
+}
+
+export interface RiskRule {
+  id: string
+  reason: string
+  score: number
+  paths?: string[]
+  words?: string[]
+  added?: string[]
+  removed?: string[]
+  changed?: string[]
+  languages?: string[]
+  source: string
+}
+
+export interface RiskRuleSet {
+  rules: RiskRule[]
+  sources: string[]
+  errors: string[]
+  secretAllow: SecretAllow[]
+}
+
+export interface SecretAllow {
+  paths?: string[]
+  match?: string
+  kinds?: string[]
+  source: string
+}
+
+export interface CommitInfo {
+  sha: string
+  shortSha: string
+  author: string
+  authorEmail: string
+  date: string
+  subject: string
+  parents: string[]
+  filesChanged: number
+  linesAdded: number
+  linesRemoved: number
+}
+
+export interface CommitRange {
+  first: string
+  last: string
+}
+
+export interface CommitsResponse {
+  base: string
+  head: string
+  commits: CommitInfo[]
+  selected: CommitRange | null
+}
+
+export interface LoadCommitsRequest {
+  first?: string
+  last?: string
+  step?: "next" | "prev"
+  all?: boolean
+}
+
 export interface GitStatus {
   stagedFiles: string[]
   unstagedFiles: string[]
   currentBranch: string
+  operation?: ConflictOperation
+  conflictedFiles: string[]
   upstreamBranch?: string
   hasUpstream: boolean
   ahead: number
@@ -74,6 +302,7 @@ export type SemanticGroup =
   | "config"
   | "docs"
   | "style"
+  | "generated"
 
 export type ViewMode = "risk" | "grouped"
 
@@ -113,11 +342,15 @@ export interface SelectRepoRequest {
   repoId: string
 }
 
+export type CompareMode = "three-dot" | "two-dot" | "worktree" | "index"
+
 export interface ReloadDiffRequest {
   base?: string
   head?: string
+  mode?: CompareMode
   staged?: boolean
   unstaged?: boolean
+  options?: DiffOptions
 }
 
 export interface FilePathRequest {
@@ -149,12 +382,187 @@ export interface GitHubPROpenResponse {
   baseOid: string
   headOid: string
   mergeBaseOid: string
+  previousBaseOid?: string
+  previousHeadOid?: string
+}
+
+export interface RangeDiffSpec {
+  oldBase: string
+  oldHead: string
+  newBase: string
+  newHead: string
+  oldCommit?: string
+  newCommit?: string
+}
+
+export interface RangeDiffPair {
+  status: "unchanged" | "changed" | "added" | "removed"
+  oldIndex?: number
+  newIndex?: number
+  oldSha?: string
+  newSha?: string
+  subject: string
+}
+
+export interface RangeDiffResponse {
+  pairs: RangeDiffPair[]
+}
+
+export interface StashEntry {
+  index: number
+  ref: string
+  sha: string
+  message: string
+  branch: string
+  date: string
+  hasUntracked: boolean
+}
+
+export interface StashListResponse {
+  stashes: StashEntry[]
+  selected: string
+}
+
+export interface StashActionRequest {
+  ref: string
+  sha?: string
+}
+
+export interface StashActionResponse {
+  ok: boolean
+  output: string
+  diff: DiffResponse
+}
+
+export interface PatchInfo {
+  index: number
+  subject: string
+  author?: string
+  date?: string
+  files: number
+  applies?: boolean
+  applyError?: string
+}
+
+export interface PatchSeries {
+  name: string
+  selected: number
+  patches: PatchInfo[]
+}
+
+export interface LoadPatchRequest {
+  path?: string
+  name?: string
+  content?: string
+  index?: number
+  check?: boolean
+}
+
+export type ConflictOperation = "merge" | "rebase" | "cherry-pick"
+
+export interface ConflictFile {
+  path: string
+  hasBase: boolean
+  hasOurs: boolean
+  hasTheirs: boolean
+  blocks: number
+}
+
+export interface ConflictState {
+  operation: ConflictOperation | ""
+  oursLabel: string
+  theirsLabel: string
+  files: ConflictFile[]
+}
+
+export interface ConflictBlock {
+  index: number
+  startLine: number
+  endLine: number
+  oursLabel: string
+  theirsLabel: string
+  ours: string[]
+  base?: string[]
+  theirs: string[]
+}
+
+export interface ConflictFileDetail {
+  path: string
+  base: string | null
+  ours: string | null
+  theirs: string | null
+  merged: string
+  blocks: ConflictBlock[]
+}
+
+export interface ConflictResolution {
+  path: string
+  block?: number
+  choice: "ours" | "theirs" | "both" | "edited"
+  content?: string
+}
+
+export interface ConflictResolveResponse {
+  remainingBlocks: number
+  conflicts: ConflictState
+  diff: DiffResponse
+}
+
+export interface ConflictActionResponse {
+  ok: boolean
+  output: string
+  conflicts: ConflictState
+  diff: DiffResponse
 }
 
 export interface GitHubPRCloseRequest {
   worktreePath: string
 }
 
+export interface FileLine {
+  number: number
+  content: string
+}
+
+export interface FileLinesResponse {
+  path: string
+  side: "old" | "new"
+  start: number
+  end: number
+  totalLines: number
+  lines: FileLine[]
+}
+
+export interface FullFileResponse {
+  path: string
+  oldPath?: string
+  oldExists: boolean
+  newExists: boolean
+  oldTotalLines: number
+  newTotalLines: number
+  lines: DiffLine[]
+}
+
+export interface FixSuggestRequest {
+  path: string
+  issue: string
+}
+
+export interface FixSuggestion {
+  path: string
+  issue: string
+  patch: string
+  preview: DiffFile[]
+  applicable: boolean
+  checkOutput?: string
+}
+
+export interface FixApplyRequest {
+  path: string
+  patch: string
+  confirm: boolean
+}
+
 export interface GitAIFileNoteItem {
   commit: string
   promptId: string
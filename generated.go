@@ -0,0 +1,205 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedHeaderRe matches the markers code generators put at the top of their output.
+var generatedHeaderRe = regexp.MustCompile(`(?i)(code generated .*do not edit|@generated|autogenerated by|auto-generated by|this file was automatically generated|generated by the protocol buffer compiler)`)
+
+// generatedHeaderLines is how far into a file a generator header is looked for.
+const generatedHeaderLines = 10
+
+// generatedSuffixes are file name endings produced by common code generators.
+var generatedSuffixes = []string{
+	".pb.go", ".pb.gw.go", "_pb2.py", "_pb2_grpc.py", ".pb.ts", "_pb.js", "_pb.d.ts", "_grpc_pb.js",
+	".gen.go", "_gen.go", "_generated.go", ".generated.ts", ".g.dart", ".freezed.dart",
+	".designer.cs", ".g.cs", "zz_generated.deepcopy.go",
+	".min.js", ".min.css", ".js.map", ".css.map",
+}
+
+// generatedFiles are lockfiles and other tool-maintained files matched by base name.
+var generatedFiles = map[string]bool{
+	"package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true, "npm-shrinkwrap.json": true,
+	"go.sum": true, "cargo.lock": true, "poetry.lock": true, "pipfile.lock": true, "gemfile.lock": true,
+	"composer.lock": true, "podfile.lock": true, "pubspec.lock": true, "mix.lock": true, "flake.lock": true,
+	"gradle.lockfile": true, "packages.lock.json": true, "bun.lockb": true, "uv.lock": true,
+}
+
+// vendoredDirs are directories holding third-party code checked into the repository.
+var vendoredDirs = []string{
+	"vendor/", "third_party/", "third-party/", "thirdparty/", "node_modules/", "bower_components/",
+	"carthage/",
+}
+
+// rootVendoredDirs are vendored directories only recognized at the repository
+// root, with their exact case, because their names are common elsewhere.
+var rootVendoredDirs = []string{
+	"Pods/", // CocoaPods
+}
+
+// markGeneratedFiles flags generated, vendored and minified files using
+// .gitattributes linguist overrides, generator headers and common path patterns.
+func markGeneratedFiles(files []*DiffFile, attrs map[string]linguistAttr) {
+	for _, file := range files {
+		// Explicit .gitattributes settings win over every heuristic
+		if a, ok := attrs[file.Path]; ok {
+			if a.generated != nil {
+				if *a.generated {
+					setGenerated(file, "Marked linguist-generated in .gitattributes")
+				}
+				continue
+			}
+			if a.vendored != nil {
+				if *a.vendored {
+					setGenerated(file, "Marked linguist-vendored in .gitattributes")
+				}
+				continue
+			}
+		}
+
+		if reason := detectGeneratedHeuristic(file); reason != "" {
+			setGenerated(file, reason)
+		}
+	}
+}
+
+func setGenerated(file *DiffFile, reason string) {
+	file.Generated = true
+	file.GeneratedReason = reason
+}
+
+// detectGeneratedHeuristic returns why a file looks generated, or "" if it does not.
+func detectGeneratedHeuristic(file *DiffFile) string {
+	pathLower := strings.ToLower(file.Path)
+	baseName := filepath.Base(pathLower)
+
+	if generatedFiles[baseName] {
+		return "Lockfile maintained by a package manager"
+	}
+
+	for _, dir := range vendoredDirs {
+		if strings.HasPrefix(pathLower, dir) || strings.Contains(pathLower, "/"+dir) {
+			return "Vendored third-party code"
+		}
+	}
+	for _, dir := range rootVendoredDirs {
+		if strings.HasPrefix(file.Path, dir) {
+			return "Vendored third-party code"
+		}
+	}
+
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(baseName, suffix) {
+			if strings.HasPrefix(suffix, ".min.") || strings.HasSuffix(suffix, ".map") {
+				return "Minified or source-map file"
+			}
+			return "Generated code file name"
+		}
+	}
+
+	for _, h := range file.Hunks {
+		for _, line := range h.Lines {
+			if line.Kind == "del" || line.NewLine == 0 || line.NewLine > generatedHeaderLines {
+				continue
+			}
+			if generatedHeaderRe.MatchString(line.Content) {
+				return "Generator header (\"Code generated ... DO NOT EDIT\")"
+			}
+		}
+	}
+
+	if isMinifiedContent(file) {
+		return "Minified content (very long lines)"
+	}
+
+	return ""
+}
+
+// isMinifiedContent reports whether the added lines of a JS/CSS file are
+// few and very long, which is typical of bundler output.
+func isMinifiedContent(file *DiffFile) bool {
+	switch file.Language {
+	case "javascript", "css":
+	default:
+		return false
+	}
+
+	lines, chars := 0, 0
+	for _, h := range file.Hunks {
+		for _, line := range h.Lines {
+			if line.Kind == "add" {
+				lines++
+				chars += len(line.Content)
+			}
+		}
+	}
+	return lines > 0 && chars/lines > 500
+}
+
+type linguistAttr struct {
+	generated *bool
+	vendored  *bool
+	language  string // linguist-language override, e.g. "Python"
+}
+
+// linguistAttributes reads linguist-generated, linguist-vendored and
+// linguist-language for every file with a single git check-attr call.
+// Failures, or no repository, yield no attributes.
+func linguistAttributes(repoPath string, files []*DiffFile) map[string]linguistAttr {
+	result := map[string]linguistAttr{}
+	if repoPath == "" || len(files) == 0 {
+		return result
+	}
+
+	var input strings.Builder
+	for _, f := range files {
+		input.WriteString(f.Path)
+		input.WriteByte(0)
+	}
+
+	out, err := runGitWithInput(repoPath, input.String(), "check-attr", "-z", "--stdin", "linguist-generated", "linguist-vendored", "linguist-language")
+	if err != nil {
+		return result
+	}
+
+	// Output is a sequence of path NUL attribute NUL value NUL triples
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		path, attr, value := fields[i], fields[i+1], fields[i+2]
+
+		if attr == "linguist-language" {
+			if value != "unspecified" && value != "unset" && value != "set" {
+				a := result[path]
+				a.language = value
+				result[path] = a
+			}
+			continue
+		}
+
+		var setting *bool
+		switch value {
+		case "set", "true":
+			v := true
+			setting = &v
+		case "unset", "false":
+			v := false
+			setting = &v
+		default:
+			continue
+		}
+
+		a := result[path]
+		switch attr {
+		case "linguist-generated":
+			a.generated = setting
+		case "linguist-vendored":
+			a.vendored = setting
+		}
+		result[path] = a
+	}
+
+	return result
+}
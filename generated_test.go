@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestDetectGeneratedHeuristic(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		lines []string
+		want  string
+	}{
+		{name: "lockfile", path: "web/package-lock.json", want: "Lockfile maintained by a package manager"},
+		{name: "vendor dir", path: "vendor/github.com/x/y.go", want: "Vendored third-party code"},
+		{name: "nested node_modules", path: "web/node_modules/a/index.js", want: "Vendored third-party code"},
+		{name: "cocoapods at the root", path: "Pods/Alamofire/Source/Request.swift", want: "Vendored third-party code"},
+		{name: "pods package", path: "pkg/controller/pods/x.go"},
+		{name: "lower-case pods at the root", path: "pods/scheduler.go"},
+		{name: "protobuf output", path: "api/v1/service.pb.go", want: "Generated code file name"},
+		{name: "minified bundle", path: "static/app.min.js", want: "Minified or source-map file"},
+		{name: "hand-written _string.go", path: "parser/parse_string.go", lines: []string{"package parser"}},
+		{
+			name:  "stringer output",
+			path:  "token/kind_string.go",
+			lines: []string{"// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.", "", "package token"},
+			want:  "Generator header (\"Code generated ... DO NOT EDIT\")",
+		},
+		{name: "plain source", path: "internal/app/server.go", lines: []string{"package app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &DiffFile{Path: tt.path}
+			if len(tt.lines) > 0 {
+				files := parseDiffOutput(addedLinesPatch(tt.path, tt.lines))
+				if len(files) != 1 {
+					t.Fatalf("parsed %d files", len(files))
+				}
+				file = files[0]
+			}
+			if got := detectGeneratedHeuristic(file); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,192 +1,293 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
 // DiffData holds the complete parsed diff result.
 type DiffData struct {
-	BaseRef string      `json:"baseRef"`
-	HeadRef string      `json:"headRef"`
-	Files   []*DiffFile `json:"files"`
+	BaseRef   string `json:"baseRef"`
+	HeadRef   string `json:"headRef"`
+	Mode      string `json:"mode,omitempty"`      // Comparison mode for base/head diffs
+	BaseSHA   string `json:"baseSha,omitempty"`   // Resolved commit on the old side
+	HeadSHA   string `json:"headSha,omitempty"`   // Resolved commit on the new side; empty for index/worktree
+	MergeBase string `json:"mergeBase,omitempty"` // Set for three-dot comparisons
+
+	Options   DiffOptions    `json:"options"`
+	Commits   *CommitRange   `json:"commits,omitempty"`   // Set when reviewing individual commits
+	RangeDiff *RangeDiffSpec `json:"rangeDiff,omitempty"` // Set when reviewing an interdiff
+	Stash     string         `json:"stash,omitempty"`     // Stash commit under review
+	Patch     *PatchSeries   `json:"patch,omitempty"`     // Set when reviewing an imported patch file
+	Notice    string         `json:"notice,omitempty"`    // Caveat about how the diff was computed
+	Files     []*DiffFile    `json:"files"`
+	Filtered  FilterStats    `json:"filtered"`
+
+	RiskRuleErrors []string     `json:"riskRuleErrors,omitempty"` // Invalid risk.yaml files and rules
+	riskRules      *RiskRuleSet // Rules the heuristic analysis scores with
 }
 
 // DiffFile represents a single changed file in the diff.
 type DiffFile struct {
 	Path         string      `json:"path"`
-	OldPath      string      `json:"oldPath,omitempty"` // Set if file was renamed
-	Status       string      `json:"status"`            // added, modified, deleted, renamed
+	OldPath      string      `json:"oldPath,omitempty"` // Set if file was renamed, or the copy source
+	Status       string      `json:"status"`            // added, modified, deleted, renamed, copied, binary, modechange, symlink, submodule, typechange, conflicted
 	Language     string      `json:"language"`
 	Hunks        []*DiffHunk `json:"hunks"`
 	RawDiff      string      `json:"rawDiff"`
 	LinesAdded   int         `json:"linesAdded"`
 	LinesRemoved int         `json:"linesRemoved"`
 
+	OldMode    string           `json:"oldMode,omitempty"`    // e.g. 100644, 100755, 120000, 160000
+	NewMode    string           `json:"newMode,omitempty"`    // Empty when the file did not exist on that side
+	Similarity int              `json:"similarity,omitempty"` // Similarity index for renames and copies
+	Submodule  *SubmoduleChange `json:"submodule,omitempty"`
+	LFS        *LFSChange       `json:"lfs,omitempty"`       // Set when the file is a Git LFS pointer
+	Collapsed  bool             `json:"collapsed,omitempty"` // Hunks hidden by .diffdragonignore, or a parsed lockfile
+	TooLarge   bool             `json:"tooLarge,omitempty"`  // Over the size limits; listed with line counts only
+
+	Generated       bool   `json:"generated,omitempty"` // Generated, vendored or minified; skipped by AI enrichment by default
+	GeneratedReason string `json:"generatedReason,omitempty"`
+
 	// Populated by analysis phase
-	RiskScore     int      `json:"riskScore"`
-	RiskReasons   []string `json:"riskReasons"`
-	SemanticGroup string   `json:"semanticGroup"`
+	RiskScore     int                `json:"riskScore"`
+	RiskReasons   []string           `json:"riskReasons"`
+	RiskEvidence  []RiskEvidence     `json:"riskEvidence,omitempty"` // What the heuristic rules matched
+	Secrets       []SecretFinding    `json:"secrets,omitempty"`      // Credentials on added lines; the file is maximum risk
+	secretValues  []string           // Every secret found, past the findings cap too; redacted from AI prompts
+	APIChanges    []APIChange        `json:"apiChanges,omitempty"`   // Changes to exported Go declarations
+	Dependencies  []DependencyChange `json:"dependencies,omitempty"` // Set, possibly empty, for parsed manifests and lockfiles
+	SemanticGroup string             `json:"semanticGroup"`
 
 	// Populated by AI phase
 	Summary   string   `json:"summary,omitempty"`
 	Checklist []string `json:"checklist,omitempty"`
 }
 
+// SubmoduleChange describes a submodule pointer bump as reported by --submodule=log.
+type SubmoduleChange struct {
+	OldCommit string   `json:"oldCommit,omitempty"`
+	NewCommit string   `json:"newCommit,omitempty"`
+	Commits   []string `json:"commits"`          // "> subject" for added commits, "< subject" for removed ones
+	Rewind    bool     `json:"rewind,omitempty"` // The pointer moved backwards or onto a diverged history
+	Note      string   `json:"note,omitempty"`   // e.g. "new submodule", "commits not present"
+}
+
 // DiffHunk represents a single hunk within a file diff.
 type DiffHunk struct {
 	Header  string `json:"header"`            // @@ line
 	Content string `json:"content"`           // The actual diff content
 	Summary string `json:"summary,omitempty"` // AI-generated summary
 
+	// Parsed from the @@ -oldStart,oldLines +newStart,newLines @@ header
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Section  string     `json:"section,omitempty"` // Function context after the header
+	Lines    []DiffLine `json:"lines"`
+
 	LinesAdded   int `json:"linesAdded"`
 	LinesRemoved int `json:"linesRemoved"`
+
+	// Populated by analysis phase
+	RiskScore   int      `json:"riskScore"`
+	RiskReasons []string `json:"riskReasons,omitempty"`
+}
+
+// DiffLine is a single line within a hunk with its position in the old and new file.
+type DiffLine struct {
+	Kind           string `json:"kind"`              // context, add, del
+	OldLine        int    `json:"oldLine,omitempty"` // 0 for added lines
+	NewLine        int    `json:"newLine,omitempty"` // 0 for deleted lines
+	Content        string `json:"content"`           // Line text without the +/-/space prefix
+	NoNewlineAtEOF bool   `json:"noNewlineAtEof,omitempty"`
+
+	Changes []LineSpan `json:"changes,omitempty"` // Word-level changed spans for paired add/del lines
 }
 
 // ParseGitDiff executes git diff and parses the output into structured data.
 func ParseGitDiff(cfg *Config) (*DiffData, error) {
-	raw, err := runGitDiff(cfg)
+	if cfg.Patch != nil {
+		return ParsePatchImport(cfg)
+	}
+
+	cmp, err := resolveComparison(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	data := &DiffData{
-		BaseRef: cfg.Base,
-		HeadRef: cfg.Head,
+	files, err := runGitDiff(cfg, cmp)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.Staged && cfg.Unstaged {
-		data.BaseRef = "HEAD"
-		data.HeadRef = "working tree"
-	} else if cfg.Staged {
-		data.BaseRef = "staged"
-		data.HeadRef = "index"
-	} else if cfg.Unstaged {
-		data.BaseRef = "index"
-		data.HeadRef = "working tree"
+	data := &DiffData{
+		BaseRef:   cmp.BaseLabel,
+		HeadRef:   cmp.HeadLabel,
+		Mode:      cmp.Mode,
+		BaseSHA:   cmp.BaseSHA,
+		HeadSHA:   cmp.HeadSHA,
+		MergeBase: cmp.MergeBase,
+		Options:   cfg.Diff,
+		Commits:   cfg.Commits,
+		RangeDiff: cfg.RangeDiff,
+		Stash:     cfg.Stash,
+		Notice:    cmp.Notice,
+		Files:     files,
 	}
-
-	data.Files = parseDiffOutput(raw)
 	if data.Files == nil {
 		data.Files = []*DiffFile{}
 	}
-	return data, nil
-}
 
-// runGitDiff executes the appropriate git diff command and returns raw output.
-func runGitDiff(cfg *Config) (string, error) {
-	if cfg.Staged && cfg.Unstaged {
-		return runWorkingTreeDiff(cfg)
+	// Drop or collapse files listed in .diffdragonignore before any analysis runs
+	rules, err := loadIgnoreRules(cfg.RepoPath)
+	if err != nil {
+		return nil, err
 	}
+	applyIgnoreRules(data, rules)
+	attrs := linguistAttributes(cfg.RepoPath, data.Files)
+	refineFileLanguages(cfg.RepoPath, cmp.New, data.Files, attrs)
+	markGeneratedFiles(data.Files, attrs)
+	detectGoAPIChanges(cfg.RepoPath, cmp.Old, cmp.New, data.Files)
+	detectDependencyChanges(cfg.RepoPath, cmp.Old, cmp.New, data.Files)
+	data.setRiskRules(LoadRiskRules(cfg.RepoPath))
 
-	if cfg.Unstaged {
-		return runUnstagedDiff(cfg)
+	if excluded, err := countPathspecExcluded(cfg, cmp); err == nil {
+		data.Filtered.Excluded = excluded
 	}
 
-	var args []string
-	if cfg.Staged {
-		args = []string{"diff", "--staged"}
-	} else {
-		args = []string{"diff", fmt.Sprintf("%s...%s", cfg.Base, cfg.Head)}
-	}
-	args = append(args, diffArgs()...)
+	return data, nil
+}
 
-	out, err := runGitCommand(cfg.RepoPath, args...)
-	if err != nil {
-		return "", err
-	}
+// runGitDiff executes git diff for the resolved comparison and streams its
+// output into parsed files, applying the size limits from the diff options.
+func runGitDiff(cfg *Config, cmp diffComparison) ([]*DiffFile, error) {
+	stream := newDiffStream(cfg.Diff.limits())
 
-	return out, nil
-}
+	args := append([]string{"diff"}, cmp.Args...)
+	args = append(args, diffArgs(cfg.Diff)...)
+	args = withPathspecs(args, diffPathspecs(cfg.Diff))
 
-func runWorkingTreeDiff(cfg *Config) (string, error) {
-	trackedOut, err := runGitCommand(cfg.RepoPath, append([]string{"diff", "HEAD"}, diffArgs()...)...)
-	if err != nil {
-		return "", err
+	run := func(env []string) error {
+		return stream.run(cfg.RepoPath, env, args)
+	}
+	if !cmp.Untracked {
+		if err := run(nil); err != nil {
+			return nil, err
+		}
+		return stream.finish(), nil
 	}
 
-	untrackedOut, err := runGitCommand(cfg.RepoPath, "ls-files", "--others", "--exclude-standard")
+	oversized, err := withUntrackedIndex(cfg, run)
 	if err != nil {
-		return "", fmt.Errorf("git ls-files failed: %w", err)
+		return nil, err
 	}
+	return append(stream.finish(), oversized...), nil
+}
 
-	chunks := []string{}
-	if strings.TrimSpace(trackedOut) != "" {
-		chunks = append(chunks, strings.TrimRight(trackedOut, "\n"))
+// withUntrackedIndex runs fn against a copy of the index in which untracked,
+// non-ignored files are added as intent-to-add, so a single git diff shows
+// them as new files. Files over the size limit are left out and returned as
+// too-large entries without being read.
+func withUntrackedIndex(cfg *Config, fn func(env []string) error) ([]*DiffFile, error) {
+	lsArgs := withPathspecs([]string{"ls-files", "-z", "--others", "--exclude-standard"}, diffPathspecs(cfg.Diff))
+	untrackedOut, err := runGitCommand(cfg.RepoPath, lsArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+	untracked := splitGitNulls(untrackedOut)
+	if len(untracked) == 0 {
+		return nil, fn(nil)
 	}
 
-	for _, relPath := range splitGitLines(untrackedOut) {
-		if relPath == "" {
+	var input strings.Builder
+	oversized := []*DiffFile{}
+	for _, relPath := range untracked {
+		info, err := os.Lstat(filepath.Join(cfg.RepoPath, relPath))
+		if err == nil && info.Mode().IsRegular() && cfg.Diff.MaxFileBytes > 0 && info.Size() > int64(cfg.Diff.MaxFileBytes) {
+			oversized = append(oversized, oversizedUntrackedFile(cfg.RepoPath, relPath))
 			continue
 		}
-
-		noIndexArgs := []string{"diff", "--no-index"}
-		noIndexArgs = append(noIndexArgs, diffArgs()...)
-		noIndexArgs = append(noIndexArgs, "/dev/null", relPath)
-
-		out, diffErr := runGitCommand(cfg.RepoPath, noIndexArgs...)
-		if diffErr != nil {
-			return "", diffErr
-		}
-		if strings.TrimSpace(out) != "" {
-			chunks = append(chunks, strings.TrimRight(out, "\n"))
-		}
+		input.WriteString(relPath)
+		input.WriteByte(0)
 	}
-
-	if len(chunks) == 0 {
-		return "", nil
+	if input.Len() == 0 {
+		return oversized, fn(nil)
 	}
 
-	return strings.Join(chunks, "\n") + "\n", nil
-}
-
-func runUnstagedDiff(cfg *Config) (string, error) {
-	trackedOut, err := runGitCommand(cfg.RepoPath, append([]string{"diff"}, diffArgs()...)...)
+	indexPath, err := runGit(cfg.RepoPath, "rev-parse", "--git-path", "index")
 	if err != nil {
-		return "", err
-	}
-
-	untrackedOut, err := runGitCommand(cfg.RepoPath, "ls-files", "--others", "--exclude-standard")
-	if err != nil {
-		return "", fmt.Errorf("git ls-files failed: %w", err)
+		return nil, err
 	}
-
-	chunks := []string{}
-	if strings.TrimSpace(trackedOut) != "" {
-		chunks = append(chunks, strings.TrimRight(trackedOut, "\n"))
+	indexPath = strings.TrimSpace(indexPath)
+	if !filepath.IsAbs(indexPath) {
+		indexPath = filepath.Join(cfg.RepoPath, indexPath)
 	}
 
-	for _, relPath := range splitGitLines(untrackedOut) {
-		if relPath == "" {
-			continue
+	err = withScratchIndex(func(env []string) error {
+		scratch := strings.TrimPrefix(env[0], "GIT_INDEX_FILE=")
+		if data, err := os.ReadFile(indexPath); err == nil {
+			if err := os.WriteFile(scratch, data, 0o600); err != nil {
+				return fmt.Errorf("failed to copy index: %w", err)
+			}
+			// Git compares entry times with the index mtime to spot racily clean
+			// files; a fresh mtime would hide edits made right after staging
+			if info, err := os.Stat(indexPath); err == nil {
+				_ = os.Chtimes(scratch, info.ModTime(), info.ModTime())
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read index: %w", err)
 		}
 
-		noIndexArgs := []string{"diff", "--no-index"}
-		noIndexArgs = append(noIndexArgs, diffArgs()...)
-		noIndexArgs = append(noIndexArgs, "/dev/null", relPath)
-
-		out, diffErr := runGitCommand(cfg.RepoPath, noIndexArgs...)
-		if diffErr != nil {
-			return "", diffErr
-		}
-		if strings.TrimSpace(out) != "" {
-			chunks = append(chunks, strings.TrimRight(out, "\n"))
+		// Paths are literal file names, not pathspec patterns
+		if _, err := gitStdout(cfg.RepoPath, env, input.String(), "--literal-pathspecs", "add", "--intent-to-add", "--pathspec-from-file=-", "--pathspec-file-nul"); err != nil {
+			return err
 		}
+		return fn(env)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return oversized, nil
+}
 
-	if len(chunks) == 0 {
-		return "", nil
+// oversizedUntrackedFile describes an untracked file too large to diff,
+// peeking only at its start to tell text from binary.
+func oversizedUntrackedFile(repoPath string, relPath string) *DiffFile {
+	file := &DiffFile{
+		Path:        relPath,
+		Status:      "added",
+		NewMode:     "100644",
+		Language:    detectLanguage(relPath),
+		Hunks:       []*DiffHunk{},
+		RiskReasons: []string{},
+		TooLarge:    true,
+	}
+	if f, err := os.Open(filepath.Join(repoPath, relPath)); err == nil {
+		head := make([]byte, 8000) // The same amount git inspects
+		n, _ := f.Read(head)
+		f.Close()
+		if bytes.IndexByte(head[:n], 0) >= 0 {
+			file.Status = "binary"
+		}
 	}
-
-	return strings.Join(chunks, "\n") + "\n", nil
+	return file
 }
 
-func diffArgs() []string {
-	// Add unified context and detect renames.
+func diffArgs(opts DiffOptions) []string {
+	// Context, whitespace, rename/copy detection and algorithm come from the options.
+	// Log submodule commits and emit NUL-separated raw metadata ahead of the
+	// patch so paths are unambiguous.
 	// Force a parseable diff regardless of user git config.
-	return []string{"-U3", "--find-renames", "--no-color", "--no-ext-diff"}
+	args := opts.gitArgs()
+	return append(args, "--submodule=log", "--patch-with-raw", "-z", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/")
 }
 
 func runGitCommand(repoPath string, args ...string) (string, error) {
@@ -207,18 +308,148 @@ func runGitCommand(repoPath string, args ...string) (string, error) {
 
 // parseDiffOutput splits raw git diff output into structured DiffFile and DiffHunk objects.
 func parseDiffOutput(raw string) []*DiffFile {
-	var files []*DiffFile
-
-	// Split by "diff --git" markers
-	parts := strings.Split(raw, "diff --git ")
-	for _, part := range parts[1:] { // Skip the first empty element
-		file := parseFileDiff("diff --git " + part)
-		if file != nil {
-			files = append(files, file)
+	stream := newDiffStream(diffLimits{})
+	stream.read(strings.NewReader(raw))
+	return stream.finish()
+}
+
+// applyRawDiffEntry overrides the header-derived paths and status with the raw metadata.
+func applyRawDiffEntry(file *DiffFile, entry rawDiffEntry) {
+	file.Path = entry.Path
+	file.OldPath = entry.OldPath
+	file.Language = detectLanguage(file.Path)
+	file.OldMode = normalizeGitMode(entry.OldMode)
+	file.NewMode = normalizeGitMode(entry.NewMode)
+	if entry.Score > 0 {
+		file.Similarity = entry.Score
+	}
+
+	if file.Status == "binary" {
+		return
+	}
+	switch entry.Status {
+	case "A":
+		file.Status = "added"
+	case "D":
+		file.Status = "deleted"
+	case "R":
+		file.Status = "renamed"
+	case "C":
+		file.Status = "copied"
+	case "T":
+		file.Status = "typechange"
+	case "U":
+		file.Status = "conflicted"
+	default:
+		file.Status = "modified"
+	}
+}
+
+// refineFileStatus derives the statuses that depend on file modes: submodule
+// pointer bumps, symlink target changes and permission-only changes.
+func refineFileStatus(file *DiffFile) {
+	if file.OldMode == "160000" || file.NewMode == "160000" {
+		file.Status = "submodule"
+		return
+	}
+	if file.Status != "modified" {
+		return
+	}
+	if file.OldMode == "120000" && file.NewMode == "120000" {
+		file.Status = "symlink"
+		return
+	}
+	if file.OldMode != "" && file.NewMode != "" && file.OldMode != file.NewMode && len(file.Hunks) == 0 {
+		file.Status = "modechange"
+	}
+}
+
+// mergeFileDiff folds a follow-up section for the same path into file.
+func mergeFileDiff(file *DiffFile, other *DiffFile) {
+	file.Hunks = append(file.Hunks, other.Hunks...)
+	if other.RawDiff != "" {
+		if file.RawDiff != "" {
+			file.RawDiff = strings.TrimRight(file.RawDiff, "\n") + "\n"
+		}
+		file.RawDiff += other.RawDiff
+	}
+	file.LinesAdded += other.LinesAdded
+	file.LinesRemoved += other.LinesRemoved
+	file.TooLarge = file.TooLarge || other.TooLarge
+	if other.LFS != nil {
+		if file.LFS == nil {
+			file.LFS = other.LFS
+		} else {
+			file.LFS.NewOID, file.LFS.NewSize = other.LFS.NewOID, other.LFS.NewSize
+		}
+	}
+}
+
+var (
+	submoduleHeaderRe  = regexp.MustCompile(`^Submodule (.+) ([0-9a-f]{7,})(\.\.\.?)([0-9a-f]{7,})(?: \(([^)]*)\))?:?$`)
+	submoduleContentRe = regexp.MustCompile(`^Submodule (.+) contains (modified|untracked) content$`)
+)
+
+// extractSubmoduleLogs removes the "Submodule <path> a..b:" blocks written by
+// --submodule=log from the patch and returns them keyed by path.
+func extractSubmoduleLogs(patch string) (string, map[string]*SubmoduleChange) {
+	submodules := map[string]*SubmoduleChange{}
+	if !strings.Contains(patch, "Submodule ") {
+		return patch, submodules
+	}
+
+	var kept []string
+	var current *SubmoduleChange
+	for _, line := range strings.Split(patch, "\n") {
+		if m := submoduleHeaderRe.FindStringSubmatch(line); m != nil {
+			// git writes "..." for a diverged history and "(rewind)" after a fast-backward
+			diverged := m[3] == "..." && m[5] != "new submodule" && m[5] != "submodule deleted"
+			current = &SubmoduleChange{
+				OldCommit: normalizeObjectID(m[2]),
+				NewCommit: normalizeObjectID(m[4]),
+				Commits:   []string{},
+				Rewind:    diverged || m[5] == "rewind",
+				Note:      m[5],
+			}
+			submodules[m[1]] = current
+			continue
+		}
+		if m := submoduleContentRe.FindStringSubmatch(line); m != nil {
+			current = nil
+			if existing, ok := submodules[m[1]]; ok {
+				existing.Note = strings.TrimSpace(existing.Note + "; contains " + m[2] + " content")
+			} else {
+				submodules[m[1]] = &SubmoduleChange{Commits: []string{}, Note: "contains " + m[2] + " content"}
+			}
+			continue
 		}
+		if current != nil {
+			if strings.HasPrefix(line, "  > ") || strings.HasPrefix(line, "  < ") {
+				current.Commits = append(current.Commits, strings.TrimPrefix(line, "  "))
+				continue
+			}
+			current = nil
+		}
+		kept = append(kept, line)
 	}
 
-	return files
+	return strings.Join(kept, "\n"), submodules
+}
+
+// normalizeGitMode maps git's all-zero placeholder mode to "".
+func normalizeGitMode(mode string) string {
+	if strings.Trim(mode, "0") == "" {
+		return ""
+	}
+	return mode
+}
+
+// normalizeObjectID maps git's all-zero placeholder object id to "".
+func normalizeObjectID(id string) string {
+	if strings.Trim(id, "0") == "" {
+		return ""
+	}
+	return id
 }
 
 // parseFileDiff parses a single file's diff section.
@@ -232,20 +463,18 @@ func parseFileDiff(section string) *DiffFile {
 		RiskReasons: []string{}, // Initialize as empty (indicates "waiting for analysis")
 	}
 
-	// Parse the "diff --git a/path b/path" header
-	header := lines[0]
-	parts := strings.Fields(header)
-	if len(parts) >= 4 {
-		file.Path = normalizeGitDiffPath(parts[3])
-		oldPath := normalizeGitDiffPath(parts[2])
-		if oldPath != file.Path {
-			file.OldPath = oldPath
-		}
+	// Parse the "diff --git a/path b/path" header. Metadata lines below are
+	// unambiguous and take precedence when present.
+	header := strings.TrimPrefix(lines[0], "diff --git ")
+	if src, dst, _, ok := decodeDiffHeaderPaths(header); ok {
+		file.Path = stripDiffPrefix(dst)
+		file.OldPath = stripDiffPrefix(src)
 	}
 
 	// Determine file status and parse metadata lines
 	file.Status = "modified"
 	var diffBodyStart int
+	var minusPath, plusPath string
 
 	for i, line := range lines[1:] {
 		idx := i + 1
@@ -253,8 +482,15 @@ func parseFileDiff(section string) *DiffFile {
 			file.Status = "added"
 		} else if strings.HasPrefix(line, "deleted file") {
 			file.Status = "deleted"
-		} else if strings.HasPrefix(line, "rename from") {
+		} else if strings.HasPrefix(line, "rename from ") {
 			file.Status = "renamed"
+			file.OldPath = decodeGitPathToken(strings.TrimPrefix(line, "rename from "))
+		} else if strings.HasPrefix(line, "rename to ") {
+			file.Path = decodeGitPathToken(strings.TrimPrefix(line, "rename to "))
+		} else if strings.HasPrefix(line, "--- ") {
+			minusPath = stripDiffPrefix(decodeGitPathToken(strings.TrimPrefix(line, "--- ")))
+		} else if strings.HasPrefix(line, "+++ ") {
+			plusPath = stripDiffPrefix(decodeGitPathToken(strings.TrimPrefix(line, "+++ ")))
 		} else if strings.HasPrefix(line, "@@") {
 			diffBodyStart = idx
 			break
@@ -265,6 +501,18 @@ func parseFileDiff(section string) *DiffFile {
 		}
 	}
 
+	if plusPath != "" && plusPath != "/dev/null" {
+		file.Path = plusPath
+	} else if minusPath != "" && minusPath != "/dev/null" {
+		file.Path = minusPath
+	}
+	if minusPath != "" && minusPath != "/dev/null" {
+		file.OldPath = minusPath
+	}
+	if file.OldPath == file.Path || file.OldPath == "/dev/null" {
+		file.OldPath = ""
+	}
+
 	// Detect language from file extension
 	file.Language = detectLanguage(file.Path)
 
@@ -274,6 +522,7 @@ func parseFileDiff(section string) *DiffFile {
 		file.Hunks = parseHunks(hunkLines)
 		file.RawDiff = strings.Join(hunkLines, "\n")
 	}
+	detectLFSPointer(file)
 
 	// Count total lines added/removed
 	for _, h := range file.Hunks {
@@ -281,6 +530,13 @@ func parseFileDiff(section string) *DiffFile {
 		file.LinesRemoved += h.LinesRemoved
 	}
 
+	// Highlight word-level changes within modified lines
+	if file.LinesAdded+file.LinesRemoved <= maxIntraLineFileLines {
+		for _, h := range file.Hunks {
+			computeIntraLineChanges(h)
+		}
+	}
+
 	return file
 }
 
@@ -288,6 +544,7 @@ func parseFileDiff(section string) *DiffFile {
 func parseHunks(lines []string) []*DiffHunk {
 	var hunks []*DiffHunk
 	var current *DiffHunk
+	var oldLine, newLine, oldRemaining, newRemaining int
 
 	for _, line := range lines {
 		if strings.HasPrefix(line, "@@") {
@@ -297,14 +554,40 @@ func parseHunks(lines []string) []*DiffHunk {
 			}
 			current = &DiffHunk{
 				Header: line,
+				Lines:  []DiffLine{},
+			}
+			if oldStart, oldCount, newStart, newCount, section, ok := parseHunkHeader(line); ok {
+				current.OldStart, current.OldLines = oldStart, oldCount
+				current.NewStart, current.NewLines = newStart, newCount
+				current.Section = section
 			}
+			oldLine, newLine = current.OldStart, current.NewStart
+			oldRemaining, newRemaining = current.OldLines, current.NewLines
 		} else if current != nil {
 			current.Content += line + "\n"
 
-			if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			switch {
+			case strings.HasPrefix(line, "\\"):
+				// "\ No newline at end of file" applies to the preceding line
+				if n := len(current.Lines); n > 0 {
+					current.Lines[n-1].NoNewlineAtEOF = true
+				}
+			case strings.HasPrefix(line, "+") && newRemaining > 0:
+				current.Lines = append(current.Lines, DiffLine{Kind: "add", NewLine: newLine, Content: line[1:]})
 				current.LinesAdded++
-			} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+				newLine++
+				newRemaining--
+			case strings.HasPrefix(line, "-") && oldRemaining > 0:
+				current.Lines = append(current.Lines, DiffLine{Kind: "del", OldLine: oldLine, Content: line[1:]})
 				current.LinesRemoved++
+				oldLine++
+				oldRemaining--
+			case strings.HasPrefix(line, " ") && oldRemaining > 0 && newRemaining > 0:
+				current.Lines = append(current.Lines, DiffLine{Kind: "context", OldLine: oldLine, NewLine: newLine, Content: line[1:]})
+				oldLine++
+				newLine++
+				oldRemaining--
+				newRemaining--
 			}
 		}
 	}
@@ -316,6 +599,48 @@ func parseHunks(lines []string) []*DiffHunk {
 	return hunks
 }
 
+// parseHunkHeader parses "@@ -a,b +c,d @@ section" into its ranges and function context.
+// Omitted counts default to 1, as in git's own output.
+func parseHunkHeader(header string) (oldStart, oldLines, newStart, newLines int, section string, ok bool) {
+	if !strings.HasPrefix(header, "@@ ") {
+		return 0, 0, 0, 0, "", false
+	}
+	rest := header[3:]
+	end := strings.Index(rest, " @@")
+	if end < 0 {
+		return 0, 0, 0, 0, "", false
+	}
+	ranges := strings.Fields(rest[:end])
+	section = strings.TrimSpace(rest[end+3:])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return 0, 0, 0, 0, "", false
+	}
+
+	var okOld, okNew bool
+	oldStart, oldLines, okOld = parseHunkRange(ranges[0][1:])
+	newStart, newLines, okNew = parseHunkRange(ranges[1][1:])
+	if !okOld || !okNew {
+		return 0, 0, 0, 0, "", false
+	}
+	return oldStart, oldLines, newStart, newLines, section, true
+}
+
+func parseHunkRange(r string) (int, int, bool) {
+	startText, countText, hasCount := strings.Cut(r, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !hasCount {
+		return start, 1, true
+	}
+	count, err := strconv.Atoi(countText)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, count, true
+}
+
 // Branch represents a git branch.
 type Branch struct {
 	Name     string `json:"name"`
@@ -392,69 +717,3 @@ func branchExists(repoPath string, branch string) bool {
 	cmd.Dir = repoPath
 	return cmd.Run() == nil
 }
-
-// detectLanguage guesses the programming language from the file extension.
-func detectLanguage(path string) string {
-	ext := strings.ToLower(filepath.Ext(path))
-
-	langMap := map[string]string{
-		".go":         "go",
-		".py":         "python",
-		".js":         "javascript",
-		".ts":         "typescript",
-		".tsx":        "typescript",
-		".jsx":        "javascript",
-		".rs":         "rust",
-		".rb":         "ruby",
-		".java":       "java",
-		".kt":         "kotlin",
-		".swift":      "swift",
-		".c":          "c",
-		".cpp":        "cpp",
-		".h":          "c",
-		".cs":         "csharp",
-		".php":        "php",
-		".sql":        "sql",
-		".sh":         "bash",
-		".bash":       "bash",
-		".zsh":        "bash",
-		".yaml":       "yaml",
-		".yml":        "yaml",
-		".json":       "json",
-		".toml":       "toml",
-		".xml":        "xml",
-		".html":       "html",
-		".css":        "css",
-		".scss":       "css",
-		".md":         "markdown",
-		".proto":      "protobuf",
-		".tf":         "terraform",
-		".dockerfile": "dockerfile",
-	}
-
-	if lang, ok := langMap[ext]; ok {
-		return lang
-	}
-
-	// Check filename-based detection
-	base := strings.ToLower(filepath.Base(path))
-	if base == "dockerfile" {
-		return "dockerfile"
-	}
-	if base == "makefile" {
-		return "makefile"
-	}
-
-	return "plaintext"
-}
-
-func normalizeGitDiffPath(token string) string {
-	token = strings.Trim(token, "\"")
-	if token == "/dev/null" {
-		return token
-	}
-	if slash := strings.Index(token, "/"); slash >= 0 && slash < len(token)-1 {
-		return token[slash+1:]
-	}
-	return token
-}
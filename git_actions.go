@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -11,6 +12,8 @@ type GitStatus struct {
 	StagedFiles    []string `json:"stagedFiles"`
 	UnstagedFiles  []string `json:"unstagedFiles"`
 	CurrentBranch  string   `json:"currentBranch"`
+	Operation      string   `json:"operation,omitempty"` // merge, rebase or cherry-pick in progress
+	Conflicted     []string `json:"conflictedFiles"`
 	UpstreamBranch string   `json:"upstreamBranch,omitempty"`
 	HasUpstream    bool     `json:"hasUpstream"`
 	Ahead          int      `json:"ahead"`
@@ -24,12 +27,12 @@ type SyncResult struct {
 }
 
 func GetGitStatus(repoPath string) (GitStatus, error) {
-	stagedOut, err := runGit(repoPath, "diff", "--name-only", "--cached")
+	stagedOut, err := runGit(repoPath, "diff", "--name-only", "-z", "--cached")
 	if err != nil {
 		return GitStatus{}, fmt.Errorf("failed to get staged files: %w", err)
 	}
 
-	unstagedOut, err := runGit(repoPath, "diff", "--name-only")
+	unstagedOut, err := runGit(repoPath, "diff", "--name-only", "-z")
 	if err != nil {
 		return GitStatus{}, fmt.Errorf("failed to get unstaged files: %w", err)
 	}
@@ -39,10 +42,17 @@ func GetGitStatus(repoPath string) (GitStatus, error) {
 		return GitStatus{}, fmt.Errorf("failed to get current branch: %w", err)
 	}
 
+	conflictedOut, err := runGit(repoPath, "diff", "--name-only", "-z", "--diff-filter=U")
+	if err != nil {
+		return GitStatus{}, fmt.Errorf("failed to get conflicted files: %w", err)
+	}
+
 	status := GitStatus{
-		StagedFiles:   splitGitLines(stagedOut),
-		UnstagedFiles: splitGitLines(unstagedOut),
+		StagedFiles:   splitGitNulls(stagedOut),
+		UnstagedFiles: splitGitNulls(unstagedOut),
 		CurrentBranch: strings.TrimSpace(branchOut),
+		Operation:     DetectOperation(repoPath),
+		Conflicted:    splitGitNulls(conflictedOut),
 	}
 
 	upstreamOut, upstreamErr := runGit(repoPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
@@ -110,6 +120,30 @@ func Commit(repoPath string, message string) (string, error) {
 	return out, nil
 }
 
+func ApplyStash(repoPath string, ref string) (string, error) {
+	out, err := runGit(repoPath, "stash", "apply", ref)
+	if err != nil {
+		return out, fmt.Errorf("failed to apply %s: %w", ref, err)
+	}
+	return out, nil
+}
+
+func PopStash(repoPath string, ref string) (string, error) {
+	out, err := runGit(repoPath, "stash", "pop", ref)
+	if err != nil {
+		return out, fmt.Errorf("failed to pop %s: %w", ref, err)
+	}
+	return out, nil
+}
+
+func DropStash(repoPath string, ref string) (string, error) {
+	out, err := runGit(repoPath, "stash", "drop", ref)
+	if err != nil {
+		return out, fmt.Errorf("failed to drop %s: %w", ref, err)
+	}
+	return out, nil
+}
+
 func Push(repoPath string, status GitStatus) (string, error) {
 	if status.HasUpstream {
 		out, err := runGit(repoPath, "push")
@@ -180,6 +214,28 @@ func runGit(repoPath string, args ...string) (string, error) {
 	return string(out), nil
 }
 
+func runGitEnv(repoPath string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+func runGitWithInput(repoPath string, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
 func splitGitLines(raw string) []string {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed == "" {
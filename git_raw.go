@@ -0,0 +1,213 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// rawDiffEntry is one record from `git diff --raw -z`.
+type rawDiffEntry struct {
+	OldMode string
+	NewMode string
+	OldSHA  string
+	NewSHA  string
+	Status  string // Single status letter: A, C, D, M, R, T, U, X
+	Score   int    // Similarity percentage for renames and copies
+	OldPath string // Source path for renames and copies
+	Path    string
+}
+
+// headerKey returns the decoded "a/<src> b/<dst>" text git writes on the
+// diff --git line for this entry.
+func (e rawDiffEntry) headerKey() string {
+	src := e.Path
+	if e.OldPath != "" {
+		src = e.OldPath
+	}
+	return "a/" + src + " b/" + e.Path
+}
+
+// pathCount is the number of NUL-terminated paths following the record.
+func (e rawDiffEntry) pathCount() int {
+	if e.Status == "R" || e.Status == "C" {
+		return 2
+	}
+	return 1
+}
+
+// parseRawDiffMeta parses the ":<modes> <shas> <status>" part of a raw record,
+// without the leading colon or trailing NUL.
+func parseRawDiffMeta(meta string) (rawDiffEntry, bool) {
+	if strings.HasPrefix(meta, ":") {
+		return parseCombinedRawMeta(meta)
+	}
+	fields := strings.Fields(meta)
+	if len(fields) < 5 {
+		return rawDiffEntry{}, false
+	}
+
+	entry := rawDiffEntry{
+		OldMode: fields[0],
+		NewMode: fields[1],
+		OldSHA:  fields[2],
+		NewSHA:  fields[3],
+		Status:  fields[4][:1],
+	}
+	if len(fields[4]) > 1 {
+		entry.Score, _ = strconv.Atoi(fields[4][1:])
+	}
+	return entry, true
+}
+
+// parseCombinedRawMeta parses the "::<modes> <shas> <statuses>" record git
+// writes for an unmerged path, one mode and object per parent plus the result.
+func parseCombinedRawMeta(meta string) (rawDiffEntry, bool) {
+	parents := len(meta) - len(strings.TrimLeft(meta, ":")) + 1
+	fields := strings.Fields(strings.TrimLeft(meta, ":"))
+	if len(fields) < 2*(parents+1)+1 {
+		return rawDiffEntry{}, false
+	}
+	return rawDiffEntry{
+		OldMode: fields[0],
+		NewMode: fields[parents],
+		OldSHA:  fields[parents+1],
+		NewSHA:  fields[2*parents+1],
+		Status:  "U",
+	}, true
+}
+
+// decodeDiffHeaderPaths decodes the "a/<src> b/<dst>" part of a diff --git line.
+// Quoted tokens are unescaped. When neither token is quoted and the split is
+// ambiguous (spaces in the path), the line is returned as-is with ok=false so
+// the caller can match it against known entries or metadata lines.
+func decodeDiffHeaderPaths(rest string) (src string, dst string, key string, ok bool) {
+	if strings.HasPrefix(rest, "\"") {
+		first, remaining, good := cutQuotedGitPath(rest)
+		if !good || !strings.HasPrefix(remaining, " ") {
+			return "", "", rest, false
+		}
+		remaining = remaining[1:]
+		second := remaining
+		if strings.HasPrefix(remaining, "\"") {
+			second, _, good = cutQuotedGitPath(remaining)
+			if !good {
+				return "", "", rest, false
+			}
+		}
+		return first, second, first + " " + second, true
+	}
+
+	if strings.HasSuffix(rest, "\"") {
+		if idx := strings.LastIndex(rest, " \""); idx >= 0 {
+			if second, tail, good := cutQuotedGitPath(rest[idx+1:]); good && tail == "" {
+				first := rest[:idx]
+				return first, second, first + " " + second, true
+			}
+		}
+		return "", "", rest, false
+	}
+
+	// Unquoted: for non-renames both sides name the same path, so the line is
+	// "a/X b/X" and can be split at its midpoint.
+	if len(rest)%2 == 1 {
+		mid := len(rest) / 2
+		first, second := rest[:mid], rest[mid+1:]
+		if rest[mid] == ' ' && strings.HasPrefix(first, "a/") && strings.HasPrefix(second, "b/") && first[2:] == second[2:] {
+			return first, second, rest, true
+		}
+	}
+
+	if parts := strings.Split(rest, " "); len(parts) == 2 {
+		return parts[0], parts[1], rest, true
+	}
+
+	return "", "", rest, false
+}
+
+// decodeGitPathToken unquotes a path from a metadata line ("rename from",
+// "--- a/x" and friends), dropping the trailing tab git adds after names with spaces.
+func decodeGitPathToken(token string) string {
+	token = strings.TrimSuffix(token, "\t")
+	if strings.HasPrefix(token, "\"") {
+		if decoded, tail, ok := cutQuotedGitPath(token); ok && strings.TrimSpace(tail) == "" {
+			return decoded
+		}
+	}
+	return token
+}
+
+// cutQuotedGitPath decodes a C-style quoted string at the start of s, as
+// written by git for paths with special or non-ASCII bytes, and returns the
+// remaining text after the closing quote.
+func cutQuotedGitPath(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, "\"") {
+		return "", s, false
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return b.String(), s[i+1:], true
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", s, false
+			}
+			switch esc := s[i]; esc {
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'v':
+				b.WriteByte('\v')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '0', '1', '2', '3':
+				if i+2 >= len(s) {
+					return "", s, false
+				}
+				v, err := strconv.ParseUint(s[i:i+3], 8, 8)
+				if err != nil {
+					return "", s, false
+				}
+				b.WriteByte(byte(v))
+				i += 2
+			default:
+				b.WriteByte(esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", s, false
+}
+
+func stripDiffPrefix(path string) string {
+	if path == "/dev/null" {
+		return path
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
+
+func splitGitNulls(raw string) []string {
+	parts := strings.Split(raw, "\x00")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
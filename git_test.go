@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractSubmoduleLogs(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  SubmoduleChange
+	}{
+		{
+			name:  "fast-forward",
+			patch: "Submodule sub f18bf67..c143c67:\n  > two\n",
+			want:  SubmoduleChange{OldCommit: "f18bf67", NewCommit: "c143c67", Commits: []string{"> two"}},
+		},
+		{
+			name:  "fast-backward",
+			patch: "Submodule sub f18bf67..c143c67 (rewind):\n  < two\n",
+			want:  SubmoduleChange{OldCommit: "f18bf67", NewCommit: "c143c67", Commits: []string{"< two"}, Rewind: true, Note: "rewind"},
+		},
+		{
+			name:  "diverged",
+			patch: "Submodule sub f18bf67...c143c67:\n  < two\n  > three\n",
+			want:  SubmoduleChange{OldCommit: "f18bf67", NewCommit: "c143c67", Commits: []string{"< two", "> three"}, Rewind: true},
+		},
+		{
+			name:  "new submodule",
+			patch: "Submodule sub 0000000...c143c67 (new submodule)\n",
+			want:  SubmoduleChange{NewCommit: "c143c67", Commits: []string{}, Note: "new submodule"},
+		},
+		{
+			name:  "deleted submodule",
+			patch: "Submodule sub f18bf67...0000000 (submodule deleted)\n",
+			want:  SubmoduleChange{OldCommit: "f18bf67", Commits: []string{}, Note: "submodule deleted"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, submodules := extractSubmoduleLogs(tt.patch)
+			if rest != "" {
+				t.Errorf("remaining patch = %q, want empty", rest)
+			}
+			got, ok := submodules["sub"]
+			if !ok {
+				t.Fatalf("no change for sub in %v", submodules)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -33,6 +33,11 @@ type GitHubPROpenResult struct {
 	BaseOid      string `json:"baseOid"`
 	HeadOid      string `json:"headOid"`
 	MergeBaseOid string `json:"mergeBaseOid"`
+
+	// Set when a different head of this PR was reviewed before, e.g. before a
+	// force-push; use them as the old range of a range-diff review.
+	PreviousBaseOid string `json:"previousBaseOid,omitempty"`
+	PreviousHeadOid string `json:"previousHeadOid,omitempty"`
 }
 
 func OpenGitHubPR(repoPath string, pr string) (GitHubPROpenResult, error) {
@@ -76,13 +81,38 @@ func OpenGitHubPR(repoPath string, pr string) (GitHubPROpenResult, error) {
 		return GitHubPROpenResult{}, fmt.Errorf("failed to compute merge base: %w", err)
 	}
 
-	return GitHubPROpenResult{
+	result := GitHubPROpenResult{
 		WorktreePath: worktreePath,
 		PRNumber:     info.Number,
 		BaseOid:      info.BaseRefOid,
 		HeadOid:      info.HeadRefOid,
 		MergeBaseOid: strings.TrimSpace(mergeBaseOut),
-	}, nil
+	}
+
+	key := fmt.Sprintf("%s/%s#%d", owner, name, info.Number)
+	rec := recordPRReview(key, info.HeadRefOid, info.BaseRefOid)
+	if rec.PreviousHeadOid != "" && ensureCommitAvailable(repoPath, rec.PreviousHeadOid) && ensureCommitAvailable(repoPath, rec.PreviousBaseOid) {
+		result.PreviousBaseOid = rec.PreviousBaseOid
+		result.PreviousHeadOid = rec.PreviousHeadOid
+	}
+
+	return result, nil
+}
+
+// ensureCommitAvailable reports whether oid exists locally, fetching it from
+// origin if needed. Force-pushed-over commits are often still fetchable by id.
+func ensureCommitAvailable(repoPath string, oid string) bool {
+	if oid == "" {
+		return false
+	}
+	if _, err := runGit(repoPath, "cat-file", "-e", oid+"^{commit}"); err == nil {
+		return true
+	}
+	if _, err := runGit(repoPath, "fetch", "origin", oid); err != nil {
+		return false
+	}
+	_, err := runGit(repoPath, "cat-file", "-e", oid+"^{commit}")
+	return err == nil
 }
 
 func CloseGitHubPR(repoPath string, worktreePath string) error {
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// APIChange is a change to the exported API of a Go package.
+type APIChange struct {
+	Change   string `json:"change"`        // added, removed, changed
+	Kind     string `json:"kind"`          // func, method, type, field, interface-method, interface-embed
+	Name     string `json:"name"`          // e.g. "Client.Do" for methods, fields and interface members
+	Old      string `json:"old,omitempty"` // The declaration before the change
+	New      string `json:"new,omitempty"` // The declaration after the change
+	Breaking bool   `json:"breaking"`      // Whether code using the package may stop compiling
+	Line     int    `json:"line"`          // New line number; the old one for removals
+
+	sig string // What the change was detected on; parameter names and comments are left out
+}
+
+// goAPIDecl is one exported declaration of a Go file.
+type goAPIDecl struct {
+	kind   string
+	name   string
+	parent string // The type a field or interface member belongs to
+	sig    string // Compared to detect changes
+	text   string // Shown to the reviewer
+	line   int
+}
+
+// goAPIFile is the exported API declared by one version of a Go file.
+type goAPIFile struct {
+	decls  map[string]goAPIDecl // By kind and name
+	sealed map[string]bool      // Interfaces with unexported methods, which only their package can implement
+}
+
+// detectGoAPIChanges compares the exported declarations of the base and head
+// versions of each changed Go file. Files that are missing on a side count as
+// empty; files that do not parse are skipped.
+func detectGoAPIChanges(repoPath string, oldSrc, newSrc fileSource, files []*DiffFile) {
+	if repoPath == "" || oldSrc.Kind == "" || newSrc.Kind == "" {
+		return
+	}
+
+	var changed []*DiffFile
+	for _, file := range files {
+		file.APIChanges = nil
+		if !isGoAPIFile(file) {
+			continue
+		}
+
+		oldPath := file.Path
+		if file.OldPath != "" {
+			oldPath = file.OldPath
+		}
+		before, ok := readGoAPI(repoPath, oldSrc, oldPath, file.Status == "added")
+		if !ok {
+			continue
+		}
+		after, ok := readGoAPI(repoPath, newSrc, file.Path, file.Status == "deleted")
+		if !ok {
+			continue
+		}
+
+		file.APIChanges = diffGoAPI(before, after)
+		if len(file.APIChanges) > 0 {
+			changed = append(changed, file)
+		}
+	}
+	reconcileMovedAPI(changed)
+}
+
+// isGoAPIFile reports whether file can declare importable API: Go source
+// outside tests, testdata, vendor and internal packages.
+func isGoAPIFile(file *DiffFile) bool {
+	if !strings.HasSuffix(file.Path, ".go") || strings.HasSuffix(file.Path, "_test.go") {
+		return false
+	}
+	if file.Collapsed || file.LFS != nil || file.Status == "binary" || file.Status == "conflicted" {
+		return false
+	}
+	for _, dir := range strings.Split(path.Dir(file.Path), "/") {
+		if dir == "testdata" || dir == "vendor" || dir == "internal" {
+			return false
+		}
+	}
+	return true
+}
+
+// readGoAPI reads and parses one version of a file. A missing file is an
+// empty API; ok is false when the file cannot be read or parsed, or belongs
+// to a main package.
+func readGoAPI(repoPath string, src fileSource, filePath string, missing bool) (goAPIFile, bool) {
+	empty := goAPIFile{decls: map[string]goAPIDecl{}, sealed: map[string]bool{}}
+	if missing {
+		return empty, true
+	}
+	content, exists, err := readFileVersion(repoPath, src, filePath)
+	if err != nil {
+		return empty, false
+	}
+	if !exists {
+		return empty, true
+	}
+	return parseGoAPI(content)
+}
+
+// parseGoAPI collects the exported functions, methods, types, struct fields
+// and interface members of a Go file.
+func parseGoAPI(content string) (goAPIFile, bool) {
+	api := goAPIFile{decls: map[string]goAPIDecl{}, sealed: map[string]bool{}}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil || f.Name.Name == "main" {
+		return api, false
+	}
+
+	render := func(node ast.Node) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			return ""
+		}
+		return strings.Join(strings.Fields(buf.String()), " ")
+	}
+	line := func(node ast.Node) int {
+		return fset.Position(node.Pos()).Line
+	}
+	add := func(d goAPIDecl) {
+		api.decls[d.kind+" "+d.name] = d
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			text := render(&ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type})
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				add(goAPIDecl{kind: "func", name: d.Name.Name, sig: goFuncSig(render, d.Type), text: text, line: line(d)})
+				continue
+			}
+			recv, pointer := goReceiverName(d.Recv.List[0].Type)
+			if !ast.IsExported(recv) {
+				continue
+			}
+			sig := goFuncSig(render, d.Type)
+			if pointer {
+				sig = "(*) " + sig
+			}
+			add(goAPIDecl{kind: "method", name: recv + "." + d.Name.Name, parent: recv, sig: sig, text: text, line: line(d)})
+
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				s := spec.(*ast.TypeSpec)
+				if !s.Name.IsExported() {
+					continue
+				}
+				name := s.Name.Name
+				text := "type " + name
+				if s.TypeParams != nil {
+					text += goTypeParams(render, s.TypeParams)
+				}
+				if s.Assign.IsValid() {
+					text += " ="
+				}
+
+				switch t := s.Type.(type) {
+				case *ast.StructType:
+					text += " struct"
+					for _, field := range t.Fields.List {
+						fieldType := render(field.Type)
+						if len(field.Names) == 0 {
+							embedded := goEmbeddedName(field.Type)
+							if ast.IsExported(embedded) {
+								add(goAPIDecl{kind: "field", name: name + "." + embedded, parent: name, sig: "embedded " + fieldType, text: fieldType, line: line(field)})
+							}
+							continue
+						}
+						for _, n := range field.Names {
+							if n.IsExported() {
+								add(goAPIDecl{kind: "field", name: name + "." + n.Name, parent: name, sig: fieldType, text: n.Name + " " + fieldType, line: line(n)})
+							}
+						}
+					}
+				case *ast.InterfaceType:
+					text += " interface"
+					for _, m := range t.Methods.List {
+						if len(m.Names) == 0 {
+							embedded := render(m.Type)
+							add(goAPIDecl{kind: "interface-embed", name: name + "." + embedded, parent: name, sig: embedded, text: embedded, line: line(m)})
+							continue
+						}
+						for _, n := range m.Names {
+							if !n.IsExported() {
+								api.sealed[name] = true
+								continue
+							}
+							ft, _ := m.Type.(*ast.FuncType)
+							if ft == nil {
+								continue
+							}
+							text := n.Name + strings.TrimPrefix(render(ft), "func")
+							add(goAPIDecl{kind: "interface-method", name: name + "." + n.Name, parent: name, sig: goFuncSig(render, ft), text: text, line: line(n)})
+						}
+					}
+				default:
+					text += " " + render(s.Type)
+				}
+				add(goAPIDecl{kind: "type", name: name, sig: text, text: text, line: line(s)})
+			}
+		}
+	}
+	return api, true
+}
+
+// goFuncSig renders a function type with the parameter names left out, so
+// renaming a parameter is not a change.
+func goFuncSig(render func(ast.Node) string, ft *ast.FuncType) string {
+	types := func(fields *ast.FieldList) []string {
+		var out []string
+		if fields == nil {
+			return out
+		}
+		for _, field := range fields.List {
+			t := render(field.Type)
+			for i := 0; i < len(field.Names) || (i == 0 && len(field.Names) == 0); i++ {
+				out = append(out, t)
+			}
+		}
+		return out
+	}
+
+	sig := "func"
+	if ft.TypeParams != nil {
+		sig += goTypeParams(render, ft.TypeParams)
+	}
+	sig += "(" + strings.Join(types(ft.Params), ", ") + ")"
+	switch results := types(ft.Results); len(results) {
+	case 0:
+	case 1:
+		sig += " " + results[0]
+	default:
+		sig += " (" + strings.Join(results, ", ") + ")"
+	}
+	return sig
+}
+
+// goTypeParams renders a type parameter list such as [K comparable, V any];
+// the printer does not render a bare field list.
+func goTypeParams(render func(ast.Node) string, params *ast.FieldList) string {
+	var out []string
+	for _, field := range params.List {
+		var names []string
+		for _, n := range field.Names {
+			names = append(names, n.Name)
+		}
+		out = append(out, strings.Join(names, ", ")+" "+render(field.Type))
+	}
+	return "[" + strings.Join(out, ", ") + "]"
+}
+
+// goReceiverName returns the type name of a method receiver such as *List[T].
+func goReceiverName(expr ast.Expr) (string, bool) {
+	pointer := false
+	if star, ok := expr.(*ast.StarExpr); ok {
+		pointer, expr = true, star.X
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name, pointer
+	}
+	return "", pointer
+}
+
+// goEmbeddedName returns the field name of an embedded type: T for *pkg.T[X].
+func goEmbeddedName(expr ast.Expr) string {
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.SelectorExpr:
+			return t.Sel.Name
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// diffGoAPI lists what changed between two versions of a file's API, in
+// file order. Members of a type added or removed in this file are covered by
+// the type, as are fields and interface members of a type whose kind or
+// parameters change. Methods of a type declared in another file are always
+// compared on their own.
+func diffGoAPI(before, after goAPIFile) []APIChange {
+	var changes []APIChange
+	covered := func(d goAPIDecl) bool {
+		if d.parent == "" {
+			return false
+		}
+		o, inOld := before.decls["type "+d.parent]
+		n, inNew := after.decls["type "+d.parent]
+		if inOld != inNew {
+			return true
+		}
+		return inOld && d.kind != "method" && o.sig != n.sig
+	}
+
+	for key, o := range before.decls {
+		n, ok := after.decls[key]
+		switch {
+		case !ok:
+			if covered(o) {
+				continue
+			}
+			changes = append(changes, APIChange{Change: "removed", Kind: o.kind, Name: o.name, Old: o.text, Breaking: true, Line: o.line, sig: o.sig})
+		case o.sig != n.sig:
+			if covered(n) {
+				continue
+			}
+			// A pointer receiver becoming a value receiver only grows the method set
+			breaking := !(o.kind == "method" && o.sig == "(*) "+n.sig)
+			changes = append(changes, APIChange{Change: "changed", Kind: n.kind, Name: n.name, Old: o.text, New: n.text, Breaking: breaking, Line: n.line, sig: n.sig})
+		}
+	}
+	for key, n := range after.decls {
+		if _, ok := before.decls[key]; ok || covered(n) {
+			continue
+		}
+		// Adding a method to an interface breaks its implementations outside
+		// the package, unless unexported methods already rule those out
+		breaking := (n.kind == "interface-method" || n.kind == "interface-embed") && !before.sealed[n.parent]
+		changes = append(changes, APIChange{Change: "added", Kind: n.kind, Name: n.name, New: n.text, Breaking: breaking, Line: n.line, sig: n.sig})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Line != changes[j].Line {
+			return changes[i].Line < changes[j].Line
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// reconcileMovedAPI drops declarations that moved between files of the same
+// package, and reports moves that also changed the declaration as changes.
+func reconcileMovedAPI(files []*DiffFile) {
+	type added struct {
+		file  *DiffFile
+		index int
+	}
+	byKey := map[string]added{}
+	for _, file := range files {
+		for i, c := range file.APIChanges {
+			if c.Change == "added" {
+				byKey[path.Dir(file.Path)+"\x00"+c.Kind+" "+c.Name] = added{file, i}
+			}
+		}
+	}
+
+	drop := map[*APIChange]bool{}
+	for _, file := range files {
+		oldPath := file.Path
+		if file.OldPath != "" {
+			oldPath = file.OldPath
+		}
+		for i := range file.APIChanges {
+			removed := &file.APIChanges[i]
+			if removed.Change != "removed" {
+				continue
+			}
+			match, ok := byKey[path.Dir(oldPath)+"\x00"+removed.Kind+" "+removed.Name]
+			if !ok || match.file == file {
+				continue
+			}
+			drop[removed] = true
+			moved := &match.file.APIChanges[match.index]
+			if moved.sig == removed.sig {
+				drop[moved] = true
+				continue
+			}
+			moved.Change, moved.Old, moved.Breaking = "changed", removed.Old, true
+		}
+	}
+
+	for _, file := range files {
+		var kept []APIChange
+		for i := range file.APIChanges {
+			if !drop[&file.APIChanges[i]] {
+				kept = append(kept, file.APIChanges[i])
+			}
+		}
+		file.APIChanges = kept
+	}
+}
+
+// goAPIRisk scores a file's Go API changes. Breaking changes outweigh any
+// path heuristic; compatible ones are worth a look.
+func goAPIRisk(file *DiffFile) (int, []string) {
+	var breaking, compatible []string
+	for _, c := range file.APIChanges {
+		change := c.Change + " " + c.Kind + " " + c.Name
+		if c.Breaking {
+			breaking = append(breaking, change)
+		} else {
+			compatible = append(compatible, change)
+		}
+	}
+
+	score := 0
+	var reasons []string
+	if len(breaking) > 0 {
+		score += 35
+		reasons = append(reasons, "Breaking Go API change: "+listReasonItems(breaking))
+	}
+	if len(compatible) > 0 {
+		score += 5
+		reasons = append(reasons, "Compatible Go API change: "+listReasonItems(compatible))
+	}
+	return score, reasons
+}
+
+// listReasonItems joins the first few items for a risk reason.
+func listReasonItems(items []string) string {
+	const shown = 3
+	if len(items) <= shown {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:shown], ", "), len(items)-shown)
+}
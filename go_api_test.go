@@ -0,0 +1,339 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseGoAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		wantOK     bool
+		wantDecls  map[string]string // Key to sig
+		wantSealed []string
+	}{
+		{
+			name: "functions ignore parameter names",
+			src: `package p
+func Do(a, b int, s ...string) (n int, err error) { return }
+func helper() {}
+`,
+			wantOK:    true,
+			wantDecls: map[string]string{"func Do": "func(int, int, ...string) (int, error)"},
+		},
+		{
+			name: "methods",
+			src: `package p
+func (c *Client) Do(req *Request) error { return nil }
+func (c Client) Name() string { return "" }
+func (l *List[T]) Len() int { return 0 }
+func (c *client) Do() {}
+func (c *Client) close() {}
+`,
+			wantOK: true,
+			wantDecls: map[string]string{
+				"method Client.Do":   "(*) func(*Request) error",
+				"method Client.Name": "func() string",
+				"method List.Len":    "(*) func() int",
+			},
+		},
+		{
+			name: "types and struct fields",
+			src: `package p
+type ID string
+type Alias = ID
+type List[T any] struct{ items []T }
+type Client struct {
+	Name, Host string
+	Timeout    int
+	token      string
+	*Base
+	io.Reader
+	inner
+}
+type hidden struct{ X int }
+`,
+			wantOK: true,
+			wantDecls: map[string]string{
+				"type ID":              "type ID string",
+				"type Alias":           "type Alias = ID",
+				"type List":            "type List[T any] struct",
+				"type Client":          "type Client struct",
+				"field Client.Name":    "string",
+				"field Client.Host":    "string",
+				"field Client.Timeout": "int",
+				"field Client.Base":    "embedded *Base",
+				"field Client.Reader":  "embedded io.Reader",
+			},
+		},
+		{
+			name: "interfaces",
+			src: `package p
+type Store interface {
+	io.Closer
+	Get(key string) ([]byte, error)
+}
+type Sealed interface {
+	Get() int
+	sealed()
+}
+`,
+			wantOK: true,
+			wantDecls: map[string]string{
+				"type Store":                      "type Store interface",
+				"interface-embed Store.io.Closer": "io.Closer",
+				"interface-method Store.Get":      "func(string) ([]byte, error)",
+				"type Sealed":                     "type Sealed interface",
+				"interface-method Sealed.Get":     "func() int",
+			},
+			wantSealed: []string{"Sealed"},
+		},
+		{
+			name: "type parameters",
+			src: `package p
+type Map[K comparable, V any] struct{}
+func Keys[M ~map[K]V, K comparable, V any](m M) []K { return nil }
+`,
+			wantOK: true,
+			wantDecls: map[string]string{
+				"type Map":  "type Map[K comparable, V any] struct",
+				"func Keys": "func[M ~map[K]V, K comparable, V any](M) []K",
+			},
+		},
+		{name: "main package", src: "package main\nfunc Run() {}\n"},
+		{name: "syntax error", src: "package p\nfunc {\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, ok := parseGoAPI(tt.src)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			got := map[string]string{}
+			for key, d := range api.decls {
+				got[key] = d.sig
+			}
+			if !reflect.DeepEqual(got, tt.wantDecls) {
+				t.Errorf("decls = %v\nwant    %v", got, tt.wantDecls)
+			}
+			var sealed []string
+			for name := range api.sealed {
+				sealed = append(sealed, name)
+			}
+			if !reflect.DeepEqual(sealed, tt.wantSealed) {
+				t.Errorf("sealed = %v, want %v", sealed, tt.wantSealed)
+			}
+		})
+	}
+}
+
+// testGoAPI parses src, or returns an empty API for a missing file.
+func testGoAPI(t *testing.T, src string) goAPIFile {
+	t.Helper()
+	if src == "" {
+		return goAPIFile{decls: map[string]goAPIDecl{}, sealed: map[string]bool{}}
+	}
+	api, ok := parseGoAPI(src)
+	if !ok {
+		t.Fatalf("parseGoAPI failed for:\n%s", src)
+	}
+	return api
+}
+
+// describeAPIChanges renders changes as sorted "change kind name [breaking]" strings.
+func describeAPIChanges(changes []APIChange) []string {
+	out := []string{}
+	for _, c := range changes {
+		s := c.Change + " " + c.Kind + " " + c.Name
+		if c.Breaking {
+			s += " breaking"
+		}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestDiffGoAPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{
+			name: "methods of a type declared in another file",
+			before: `package p
+func (c *Client) Do(n int) error { return nil }
+func (c *Client) Close() {}
+`,
+			after: `package p
+func (c *Client) Do(s string) error { return nil }
+`,
+			want: []string{"changed method Client.Do breaking", "removed method Client.Close breaking"},
+		},
+		{
+			name:   "method added to a type declared in another file",
+			before: "package p\n",
+			after:  "package p\nfunc (c *Client) Reset() {}\n",
+			want:   []string{"added method Client.Reset"},
+		},
+		{
+			name: "removed type covers its members",
+			before: `package p
+type Client struct{ Name string }
+func (c *Client) Do() {}
+`,
+			after: "package p\n",
+			want:  []string{"removed type Client breaking"},
+		},
+		{
+			name:   "added type covers its members",
+			before: "",
+			after: `package p
+type Store interface{ Get() int }
+func (s Impl) Get() int { return 0 }
+`,
+			want: []string{"added method Impl.Get", "added type Store"},
+		},
+		{
+			name:   "field type change",
+			before: "package p\ntype T struct{ X int; Y string }\n",
+			after:  "package p\ntype T struct{ X int64; Y string; Z bool }\n",
+			want:   []string{"added field T.Z", "changed field T.X breaking"},
+		},
+		{
+			name:   "type kind change covers fields but not methods",
+			before: "package p\ntype T struct{ X int }\nfunc (T) M() {}\n",
+			after:  "package p\ntype T interface{ X() int }\nfunc (*T) M() {}\n",
+			want:   []string{"changed method T.M breaking", "changed type T breaking"},
+		},
+		{
+			name:   "interface method added",
+			before: "package p\ntype I interface{ A() }\n",
+			after:  "package p\ntype I interface{ A(); B() }\n",
+			want:   []string{"added interface-method I.B breaking"},
+		},
+		{
+			name:   "method added to a sealed interface",
+			before: "package p\ntype I interface{ A(); seal() }\n",
+			after:  "package p\ntype I interface{ A(); B(); seal() }\n",
+			want:   []string{"added interface-method I.B"},
+		},
+		{
+			name:   "pointer to value receiver is compatible",
+			before: "package p\nfunc (c *C) M() {}\n",
+			after:  "package p\nfunc (c C) M() {}\n",
+			want:   []string{"changed method C.M"},
+		},
+		{
+			name:   "value to pointer receiver is breaking",
+			before: "package p\nfunc (c C) M() {}\n",
+			after:  "package p\nfunc (c *C) M() {}\n",
+			want:   []string{"changed method C.M breaking"},
+		},
+		{
+			name:   "renamed parameters and unexported changes",
+			before: "package p\nfunc F(a int) {}\nfunc g() {}\n",
+			after:  "package p\n\n// F does things.\nfunc F(count int) {}\nfunc g(x int) {}\n",
+			want:   []string{},
+		},
+		{
+			name:   "type parameter constraint change",
+			before: "package p\ntype Set[T comparable] struct{}\n",
+			after:  "package p\ntype Set[T any] struct{}\n",
+			want:   []string{"changed type Set breaking"},
+		},
+		{
+			name:   "deleted file",
+			before: "package p\nfunc F() {}\ntype T int\n",
+			after:  "",
+			want:   []string{"removed func F breaking", "removed type T breaking"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeAPIChanges(diffGoAPI(testGoAPI(t, tt.before), testGoAPI(t, tt.after)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileMovedAPI(t *testing.T) {
+	type version struct {
+		path, oldPath, before, after string
+	}
+	tests := []struct {
+		name  string
+		files []version
+		want  map[string][]string // By path
+	}{
+		{
+			name: "unchanged move within a package",
+			files: []version{
+				{path: "p/a.go", before: "package p\nfunc F(n int) {}\n", after: "package p\n"},
+				{path: "p/b.go", before: "package p\n", after: "package p\nfunc F(count int) {}\n"},
+			},
+			want: map[string][]string{"p/a.go": {}, "p/b.go": {}},
+		},
+		{
+			name: "move that changes the declaration",
+			files: []version{
+				{path: "p/a.go", before: "package p\nfunc F(n int) {}\n", after: "package p\n"},
+				{path: "p/b.go", before: "package p\n", after: "package p\nfunc F(s string) {}\n"},
+			},
+			want: map[string][]string{"p/a.go": {}, "p/b.go": {"changed func F breaking"}},
+		},
+		{
+			name: "type moved with its methods",
+			files: []version{
+				{path: "p/a.go", before: "package p\ntype T struct{ X int }\nfunc (T) M() {}\n", after: "package p\n"},
+				{path: "p/b.go", before: "", after: "package p\ntype T struct{ X int }\nfunc (T) M() {}\n"},
+			},
+			want: map[string][]string{"p/a.go": {}, "p/b.go": {}},
+		},
+		{
+			name: "move to another package",
+			files: []version{
+				{path: "p/a.go", before: "package p\nfunc F() {}\n", after: "package p\n"},
+				{path: "q/a.go", before: "package q\n", after: "package q\nfunc F() {}\n"},
+			},
+			want: map[string][]string{"p/a.go": {"removed func F breaking"}, "q/a.go": {"added func F"}},
+		},
+		{
+			name: "renamed file keeps its declarations",
+			files: []version{
+				{path: "p/new.go", oldPath: "p/old.go", before: "package p\nfunc F() {}\n", after: "package p\nfunc F() {}\nfunc G() {}\n"},
+			},
+			want: map[string][]string{"p/new.go": {"added func G"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []*DiffFile
+			for _, v := range tt.files {
+				file := &DiffFile{Path: v.path, OldPath: v.oldPath}
+				file.APIChanges = diffGoAPI(testGoAPI(t, v.before), testGoAPI(t, v.after))
+				files = append(files, file)
+			}
+			reconcileMovedAPI(files)
+			got := map[string][]string{}
+			for _, file := range files {
+				got[file.Path] = describeAPIChanges(file.APIChanges)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -66,6 +68,7 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 		gitStatus := GitStatus{
 			StagedFiles:   []string{},
 			UnstagedFiles: []string{},
+			Conflicted:    []string{},
 		}
 		if repo, ok := repos.Current(); ok {
 			status, err := GetGitStatus(repo.Path)
@@ -78,9 +81,12 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			return map[string]interface{}{
 				"baseRef":       "",
 				"headRef":       "",
+				"mode":          cfg.Mode,
+				"diffOptions":   cfg.Diff,
 				"files":         []*DiffFile{},
 				"aiProvider":    cfg.AIProvider,
 				"stats":         computeStats(nil),
+				"dependencies":  summarizeDependencies(nil),
 				"gitStatus":     gitStatus,
 				"repos":         repos.List(),
 				"currentRepoId": repos.CurrentID(),
@@ -90,48 +96,65 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 		}
 
 		return map[string]interface{}{
-			"baseRef":       data.BaseRef,
-			"headRef":       data.HeadRef,
-			"files":         data.Files,
-			"aiProvider":    cfg.AIProvider,
-			"stats":         computeStats(data),
-			"gitStatus":     gitStatus,
-			"repos":         repos.List(),
-			"currentRepoId": repos.CurrentID(),
-			"aiAnalyzing":   holder.IsAIAnalyzing(),
-			"aiError":       holder.GetAILastError(),
+			"baseRef":        data.BaseRef,
+			"headRef":        data.HeadRef,
+			"mode":           data.Mode,
+			"baseSha":        data.BaseSHA,
+			"headSha":        data.HeadSHA,
+			"mergeBase":      data.MergeBase,
+			"commits":        data.Commits,
+			"rangeDiff":      data.RangeDiff,
+			"stash":          data.Stash,
+			"patch":          data.Patch,
+			"notice":         data.Notice,
+			"riskRuleErrors": data.RiskRuleErrors,
+			"diffOptions":    data.Options,
+			"files":          data.Files,
+			"aiProvider":     cfg.AIProvider,
+			"stats":          computeStats(data),
+			"dependencies":   summarizeDependencies(data),
+			"gitStatus":      gitStatus,
+			"repos":          repos.List(),
+			"currentRepoId":  repos.CurrentID(),
+			"aiAnalyzing":    holder.IsAIAnalyzing(),
+			"aiError":        holder.GetAILastError(),
+		}
+	}
+
+	// storeDiff analyzes with heuristics immediately for a fast UI response and
+	// enriches with AI in the background so loading isn't blocked.
+	storeDiff := func(diffData *DiffData) {
+		AnalyzeDiffHeuristics(diffData)
+		holder.Replace(diffData)
+		if ai != nil {
+			holder.SetAIAnalyzing(true)
+			holder.SetAILastError("")
+			go func() {
+				AnalyzeDiffAI(diffData, ai, holder)
+				holder.SetAIAnalyzing(false)
+			}()
 		}
 	}
 
 	reloadCurrentRepo := func() error {
 		repo, ok := repos.Current()
-		if !ok {
+		if !ok && cfg.Patch == nil {
 			holder.Replace(nil)
 			return nil
 		}
 
 		cfg.RepoPath = repo.Path
 		diffData, err := ParseGitDiff(cfg)
-		if err != nil && !cfg.Staged && !cfg.Unstaged {
+		if err != nil && ok && !cfg.Staged && !cfg.Unstaged {
 			cfg.Base = ResolveDefaultBaseRef(repo.Path)
 			cfg.Head = "HEAD"
+			cfg.resetReviewTarget()
 			diffData, err = ParseGitDiff(cfg)
 		}
 		if err != nil {
 			return err
 		}
-		// Analyze with heuristics immediately for fast UI response
-		AnalyzeDiffHeuristics(diffData)
-		holder.Replace(diffData)
-		// Enrich with AI in the background so repo switching isn't blocked
-		if ai != nil {
-			holder.SetAIAnalyzing(true)
-			holder.SetAILastError("")
-			go func() {
-				AnalyzeDiffAI(diffData, ai, holder)
-				holder.SetAIAnalyzing(false)
-			}()
-		}
+		storeDiff(diffData)
 		return nil
 	}
 	if !cfg.Dev {
@@ -150,6 +173,42 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 		json.NewEncoder(w).Encode(buildDiffResponse(data))
 	})
 
+	// API: diff metadata without hunks, for change sets too large to send at once
+	mux.HandleFunc("/api/diff/summary", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		resp := buildDiffResponse(holder.Get())
+		files := resp["files"].([]*DiffFile)
+		summaries := make([]DiffFileSummary, 0, len(files))
+		for _, f := range files {
+			summaries = append(summaries, summarizeDiffFile(f))
+		}
+		resp["files"] = summaries
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	// API: full hunks for a single file of the current diff
+	mux.HandleFunc("/api/diff/file", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		file := findDiffFile(holder.Get(), r.URL.Query().Get("path"))
+		if file == nil {
+			http.Error(w, "File not found in current diff", 404)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(file)
+	})
+
 	// API: list repositories and current selection
 	mux.HandleFunc("/api/repos", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -186,6 +245,7 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			http.Error(w, err.Error(), 400)
 			return
 		}
+		cfg.resetReviewTarget()
 
 		if err := reloadCurrentRepo(); err != nil {
 			http.Error(w, fmt.Sprintf("Failed to parse diff: %v", err), 500)
@@ -239,6 +299,7 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			http.Error(w, err.Error(), 404)
 			return
 		}
+		cfg.resetReviewTarget()
 
 		if err := reloadCurrentRepo(); err != nil {
 			http.Error(w, fmt.Sprintf("Failed to parse diff: %v", err), 500)
@@ -348,16 +409,39 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 		}
 
 		var req struct {
-			Base     string `json:"base"`
-			Head     string `json:"head"`
-			Staged   *bool  `json:"staged"`
-			Unstaged *bool  `json:"unstaged"`
+			Base     string          `json:"base"`
+			Head     string          `json:"head"`
+			Mode     *string         `json:"mode"`
+			Staged   *bool           `json:"staged"`
+			Unstaged *bool           `json:"unstaged"`
+			Options  json.RawMessage `json:"options"` // Fields to change; the rest keep their current values
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", 400)
 			return
 		}
 
+		if req.Mode != nil {
+			if err := validateCompareMode(*req.Mode); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+		}
+		opts := cfg.Diff
+		if len(req.Options) > 0 {
+			opts.Include = append([]string(nil), cfg.Diff.Include...)
+			opts.Exclude = append([]string(nil), cfg.Diff.Exclude...)
+			if err := json.Unmarshal(req.Options, &opts); err != nil {
+				http.Error(w, "Invalid request body", 400)
+				return
+			}
+			opts = normalizeDiffOptions(opts)
+			if err := validateDiffOptions(opts); err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+		}
+
 		repo, ok := repos.Current()
 		if !ok {
 			http.Error(w, "No repository selected", 400)
@@ -366,6 +450,7 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 		cfg.RepoPath = repo.Path
 
 		// Update config
+		cfg.resetReviewTarget()
 		cfg.Staged = req.Staged != nil && *req.Staged
 		cfg.Unstaged = req.Unstaged != nil && *req.Unstaged
 		if !cfg.Staged && !cfg.Unstaged {
@@ -376,6 +461,10 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 				cfg.Head = req.Head
 			}
 		}
+		if req.Mode != nil {
+			cfg.Mode = *req.Mode
+		}
+		cfg.Diff = opts
 
 		// Re-parse the diff
 		diffData, err := ParseGitDiff(cfg)
@@ -383,25 +472,14 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			http.Error(w, fmt.Sprintf("Failed to parse diff: %v", err), 500)
 			return
 		}
-		// Analyze with heuristics immediately for fast response
-		AnalyzeDiffHeuristics(diffData)
-		holder.Replace(diffData)
-		// Enrich with AI in the background
-		if ai != nil {
-			holder.SetAIAnalyzing(true)
-			holder.SetAILastError("")
-			go func() {
-				AnalyzeDiffAI(diffData, ai, holder)
-				holder.SetAIAnalyzing(false)
-			}()
-		}
+		storeDiff(diffData)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(buildDiffResponse(diffData))
 	})
 
-	// API: return git status for the selected repository.
-	mux.HandleFunc("/api/git/status", func(w http.ResponseWriter, r *http.Request) {
+	// API: list the commits of base..head for per-commit review
+	mux.HandleFunc("/api/commits", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
 			http.Error(w, "Method not allowed", 405)
 			return
@@ -413,44 +491,122 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			return
 		}
 
-		status, err := GetGitStatus(repo.Path)
+		commits, err := ListRangeCommits(repo.Path, cfg.Base, cfg.Head)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"base":     cfg.Base,
+			"head":     cfg.Head,
+			"commits":  commits,
+			"selected": cfg.Commits,
+		})
 	})
 
-	// API: return Git AI notes for a file between base/head refs.
-	mux.HandleFunc("/api/git-ai/file-notes", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" {
+	// API: review a single commit or a sub-range, step to the next/previous
+	// commit, or go back to the whole range
+	mux.HandleFunc("/api/commits/load", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
+		var req struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+			Step  string `json:"step"` // next, prev
+			All   bool   `json:"all"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+
 		repo, ok := repos.Current()
 		if !ok {
 			http.Error(w, "No repository selected", 400)
 			return
 		}
+		cfg.RepoPath = repo.Path
 
-		path := strings.TrimSpace(r.URL.Query().Get("path"))
-		oldPath := strings.TrimSpace(r.URL.Query().Get("oldPath"))
-		base := strings.TrimSpace(r.URL.Query().Get("base"))
-		head := strings.TrimSpace(r.URL.Query().Get("head"))
+		var selection *CommitRange
+		switch {
+		case req.All:
+		case req.Step != "":
+			commits, err := ListRangeCommits(repo.Path, cfg.Base, cfg.Head)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			selection, err = StepCommit(commits, cfg.Commits, req.Step)
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+		default:
+			if strings.TrimSpace(req.First) == "" {
+				http.Error(w, "first commit is required", 400)
+				return
+			}
+			if strings.TrimSpace(req.Last) == "" {
+				req.Last = req.First
+			}
+			first, err := resolveCommit(repo.Path, strings.TrimSpace(req.First))
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			last, err := resolveCommit(repo.Path, strings.TrimSpace(req.Last))
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			selection = &CommitRange{First: first, Last: last}
+		}
 
-		if path == "" {
-			http.Error(w, "path is required", 400)
+		cfg.resetReviewTarget()
+		cfg.Staged = false
+		cfg.Unstaged = false
+		cfg.Commits = selection
+
+		diffData, err := ParseGitDiff(cfg)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse diff: %v", err), 500)
 			return
 		}
-		if base == "" || head == "" {
-			http.Error(w, "base and head are required", 400)
+		storeDiff(diffData)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildDiffResponse(diffData))
+	})
+
+	// API: list commit pairs between two versions of a series (git range-diff)
+	mux.HandleFunc("/api/rangediff", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
-		items, err := GetGitAIFileNotes(repo.Path, base, head, path, oldPath)
+		var spec RangeDiffSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if err := validateRangeDiffSpec(spec); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		pairs, err := ListRangeDiff(repo.Path, spec)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -458,164 +614,239 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"items": items,
+			"pairs": pairs,
 		})
 	})
 
-	// API: return Git AI prompt details for a commit/prompt id.
-	mux.HandleFunc("/api/git-ai/prompt", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" {
+	// API: review the interdiff of a whole series or of one commit pair
+	mux.HandleFunc("/api/rangediff/load", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
-		repo, ok := repos.Current()
-		if !ok {
-			http.Error(w, "No repository selected", 400)
+		var spec RangeDiffSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, "Invalid request body", 400)
 			return
 		}
-
-		promptID := strings.TrimSpace(r.URL.Query().Get("promptId"))
-		commit := strings.TrimSpace(r.URL.Query().Get("commit"))
-		if promptID == "" {
-			http.Error(w, "promptId is required", 400)
+		if err := validateRangeDiffSpec(spec); err != nil {
+			http.Error(w, err.Error(), 400)
 			return
 		}
-		if commit == "" {
-			http.Error(w, "commit is required", 400)
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
 			return
 		}
+		cfg.RepoPath = repo.Path
 
-		detail, err := GetGitAIPromptDetail(repo.Path, promptID, commit)
+		cfg.resetReviewTarget()
+		cfg.Staged = false
+		cfg.Unstaged = false
+		cfg.RangeDiff = &spec
+
+		diffData, err := ParseGitDiff(cfg)
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			cfg.RangeDiff = nil
+			http.Error(w, fmt.Sprintf("Failed to compute range-diff: %v", err), 500)
 			return
 		}
+		storeDiff(diffData)
+		if spec.OldCommit == "" && spec.NewCommit == "" {
+			// The whole force-push has been shown; later opens of the PR compare from here
+			oldHead, oldErr := resolveCommit(repo.Path, spec.OldHead)
+			newHead, newErr := resolveCommit(repo.Path, spec.NewHead)
+			if oldErr == nil && newErr == nil {
+				markPRRangeDiffReviewed(oldHead, newHead)
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(detail)
+		json.NewEncoder(w).Encode(buildDiffResponse(diffData))
 	})
 
-	// API: stage a full file path.
-	mux.HandleFunc("/api/git/stage", func(w http.ResponseWriter, r *http.Request) {
+	// API: review a patch or mbox file, given as a local path or as content
+	mux.HandleFunc("/api/patch/load", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
-		repo, ok := repos.Current()
-		if !ok {
-			http.Error(w, "No repository selected", 400)
+		// A JSON body cannot be sent by a cross-site form post
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "Content-Type must be application/json", 415)
 			return
 		}
 
 		var req struct {
-			Path string `json:"path"`
+			Path    string `json:"path"`
+			Name    string `json:"name"`
+			Content string `json:"content"`
+			Index   *int   `json:"index"` // Omit to review the whole series
+			Check   bool   `json:"check"`
 		}
+		r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.Diff.MaxTotalBytes))
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", 400)
 			return
 		}
-		if strings.TrimSpace(req.Path) == "" {
-			http.Error(w, "Path is required", 400)
+
+		var imp *PatchImport
+		switch {
+		case strings.TrimSpace(req.Path) != "":
+			repo, ok := repos.Current()
+			if !ok {
+				http.Error(w, "No repository selected", 400)
+				return
+			}
+			path, err := resolvePatchPath(repo.Path, strings.TrimSpace(req.Path))
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			imp, err = ReadPatchImport(path)
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			imp.Name = strings.TrimSpace(req.Path)
+		case req.Content != "":
+			name := strings.TrimSpace(req.Name)
+			if name == "" {
+				name = "uploaded patch"
+			}
+			imp = &PatchImport{Name: name, Content: req.Content, Index: -1}
+		default:
+			http.Error(w, "path or content is required", 400)
 			return
 		}
+		if req.Index != nil {
+			if *req.Index < 0 {
+				http.Error(w, "index must not be negative", 400)
+				return
+			}
+			imp.Index = *req.Index
+		}
+		imp.Check = req.Check
 
-		if err := StageFile(repo.Path, req.Path); err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+		if repo, ok := repos.Current(); ok {
+			cfg.RepoPath = repo.Path
+		} else {
+			cfg.RepoPath = ""
 		}
 
-		if err := reloadCurrentRepo(); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+		previous := cfg.Patch
+		cfg.resetReviewTarget()
+		cfg.Staged = false
+		cfg.Unstaged = false
+		cfg.Patch = imp
+
+		diffData, err := ParseGitDiff(cfg)
+		if err != nil {
+			cfg.Patch = previous
+			http.Error(w, err.Error(), 400)
 			return
 		}
+		storeDiff(diffData)
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+		json.NewEncoder(w).Encode(buildDiffResponse(diffData))
 	})
 
-	// API: unstage a full file path.
-	mux.HandleFunc("/api/git/unstage", func(w http.ResponseWriter, r *http.Request) {
+	// API: review another patch of the imported series, or the whole series
+	mux.HandleFunc("/api/patch/select", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
-		repo, ok := repos.Current()
-		if !ok {
-			http.Error(w, "No repository selected", 400)
-			return
-		}
-
 		var req struct {
-			Path string `json:"path"`
+			Index *int `json:"index"` // Omit to review the whole series
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", 400)
 			return
 		}
-		if strings.TrimSpace(req.Path) == "" {
-			http.Error(w, "Path is required", 400)
+		if cfg.Patch == nil {
+			http.Error(w, "No patch file loaded", 400)
 			return
 		}
 
-		if err := UnstageFile(repo.Path, req.Path); err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+		previous := cfg.Patch.Index
+		cfg.Patch.Index = -1
+		if req.Index != nil {
+			if *req.Index < 0 {
+				http.Error(w, "index must not be negative", 400)
+				return
+			}
+			cfg.Patch.Index = *req.Index
 		}
 
-		if err := reloadCurrentRepo(); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+		diffData, err := ParseGitDiff(cfg)
+		if err != nil {
+			cfg.Patch.Index = previous
+			http.Error(w, err.Error(), 400)
 			return
 		}
+		storeDiff(diffData)
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+		json.NewEncoder(w).Encode(buildDiffResponse(diffData))
 	})
 
-	// API: discard all staged/unstaged changes for a file path.
-	mux.HandleFunc("/api/git/discard", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
+	// API: the effective risk rules for the current repository, read fresh
+	// so edits to risk.yaml can be checked before reloading the diff
+	mux.HandleFunc("/api/risk-rules", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
 			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
-		repo, ok := repos.Current()
-		if !ok {
-			http.Error(w, "No repository selected", 400)
-			return
+		repoPath := ""
+		if repo, ok := repos.Current(); ok {
+			repoPath = repo.Path
 		}
 
-		var req struct {
-			Path string `json:"path"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", 400)
-			return
-		}
-		if strings.TrimSpace(req.Path) == "" {
-			http.Error(w, "Path is required", 400)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoadRiskRules(repoPath))
+	})
+
+	// API: the riskiest hunks across all files, for reviewing the hot spots first
+	mux.HandleFunc("/api/hunks/hottest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
-		if err := DiscardFileChanges(repo.Path, req.Path); err != nil {
-			http.Error(w, err.Error(), 500)
+		limit, err := parseOptionalInt(r.URL.Query().Get("limit"))
+		if err != nil || limit < 0 {
+			http.Error(w, "limit must be a non-negative number", 400)
 			return
 		}
+		if limit == 0 {
+			limit = 20
+		}
 
-		if err := reloadCurrentRepo(); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(HottestHunks(holder.Get(), limit))
+	})
+
+	// API: dependency changes across the manifests and lockfiles of the diff
+	mux.HandleFunc("/api/dependencies", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+		json.NewEncoder(w).Encode(summarizeDependencies(holder.Get()))
 	})
 
-	// API: commit all staged changes and push.
-	mux.HandleFunc("/api/git/commit-push", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "POST" {
+	// API: list stash entries
+	mux.HandleFunc("/api/stash", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
 			http.Error(w, "Method not allowed", 405)
 			return
 		}
@@ -626,7 +857,679 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			return
 		}
 
-		var req struct {
+		stashes, err := ListStashes(repo.Path)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"stashes":  stashes,
+			"selected": cfg.Stash,
+		})
+	})
+
+	// API: review a stash against the commit it was made on
+	mux.HandleFunc("/api/stash/load", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		var req struct {
+			Ref string `json:"ref"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Ref) == "" {
+			http.Error(w, "Stash ref is required", 400)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+		cfg.RepoPath = repo.Path
+
+		sha, err := resolveStash(repo.Path, strings.TrimSpace(req.Ref))
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		cfg.resetReviewTarget()
+		cfg.Staged = false
+		cfg.Unstaged = false
+		cfg.Stash = sha
+
+		diffData, err := ParseGitDiff(cfg)
+		if err != nil {
+			cfg.Stash = ""
+			http.Error(w, fmt.Sprintf("Failed to parse diff: %v", err), 500)
+			return
+		}
+		storeDiff(diffData)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildDiffResponse(diffData))
+	})
+
+	// API: apply, pop or drop a stash. The optional sha guards against the
+	// ref having shifted since the list was fetched.
+	stashAction := func(action func(repoPath string, ref string) (string, error), removes bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				http.Error(w, "Method not allowed", 405)
+				return
+			}
+
+			var req struct {
+				Ref string `json:"ref"`
+				SHA string `json:"sha"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", 400)
+				return
+			}
+			ref := strings.TrimSpace(req.Ref)
+			if ref == "" {
+				http.Error(w, "Stash ref is required", 400)
+				return
+			}
+
+			repo, ok := repos.Current()
+			if !ok {
+				http.Error(w, "No repository selected", 400)
+				return
+			}
+
+			sha, err := resolveStash(repo.Path, ref)
+			if err != nil {
+				http.Error(w, err.Error(), 400)
+				return
+			}
+			if req.SHA != "" && req.SHA != sha {
+				http.Error(w, fmt.Sprintf("%s has changed since the stash list was loaded; refresh and try again", ref), 409)
+				return
+			}
+
+			out, err := action(repo.Path, ref)
+			if err != nil {
+				// A conflicting apply or pop leaves the stash in place but may
+				// have touched the working tree
+				if strings.Contains(out, "CONFLICT") || strings.Contains(out, "would be overwritten") {
+					_ = reloadCurrentRepo()
+					http.Error(w, strings.TrimSpace(out), 409)
+					return
+				}
+				http.Error(w, err.Error(), 500)
+				return
+			}
+
+			if removes && cfg.Stash == sha {
+				cfg.Stash = ""
+			}
+			if err := reloadCurrentRepo(); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"ok":     true,
+				"output": strings.TrimSpace(out),
+				"diff":   buildDiffResponse(holder.Get()),
+			})
+		}
+	}
+	mux.HandleFunc("/api/stash/apply", stashAction(ApplyStash, false))
+	mux.HandleFunc("/api/stash/pop", stashAction(PopStash, true))
+	mux.HandleFunc("/api/stash/drop", stashAction(DropStash, true))
+
+	// API: list conflicted files of an in-progress merge, rebase or cherry-pick
+	mux.HandleFunc("/api/conflicts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		state, err := GetConflictState(repo.Path)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+	})
+
+	// API: base, ours, theirs and working tree versions of a conflicted file
+	mux.HandleFunc("/api/conflicts/file", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		path := strings.TrimSpace(r.URL.Query().Get("path"))
+		if path == "" {
+			http.Error(w, "Path is required", 400)
+			return
+		}
+
+		detail, err := GetConflictFile(repo.Path, path)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(detail)
+	})
+
+	// API: resolve a conflicted file or one of its conflict blocks
+	mux.HandleFunc("/api/conflicts/resolve", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req ConflictResolution
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			http.Error(w, "Path is required", 400)
+			return
+		}
+
+		remaining, err := ResolveConflict(repo.Path, req)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+
+		state, err := GetConflictState(repo.Path)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if err := reloadCurrentRepo(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"remainingBlocks": remaining,
+			"conflicts":       state,
+			"diff":            buildDiffResponse(holder.Get()),
+		})
+	})
+
+	// API: continue or abort the operation in progress. Continuing a rebase can
+	// stop on the next commit, which is reported as 409; fetch /api/conflicts again.
+	conflictAction := func(action func(repoPath string) (string, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				http.Error(w, "Method not allowed", 405)
+				return
+			}
+
+			repo, ok := repos.Current()
+			if !ok {
+				http.Error(w, "No repository selected", 400)
+				return
+			}
+
+			out, actionErr := action(repo.Path)
+			if err := reloadCurrentRepo(); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+				return
+			}
+			state, err := GetConflictState(repo.Path)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			if actionErr != nil {
+				code := 500
+				if len(state.Files) > 0 {
+					code = 409
+				}
+				http.Error(w, actionErr.Error(), code)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"ok":        true,
+				"output":    strings.TrimSpace(out),
+				"conflicts": state,
+				"diff":      buildDiffResponse(holder.Get()),
+			})
+		}
+	}
+	mux.HandleFunc("/api/conflicts/continue", conflictAction(ContinueOperation))
+	mux.HandleFunc("/api/conflicts/abort", conflictAction(AbortOperation))
+
+	// API: return git status for the selected repository.
+	mux.HandleFunc("/api/git/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		status, err := GetGitStatus(repo.Path)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+
+	// API: return Git AI notes for a file between base/head refs.
+	mux.HandleFunc("/api/git-ai/file-notes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		path := strings.TrimSpace(r.URL.Query().Get("path"))
+		oldPath := strings.TrimSpace(r.URL.Query().Get("oldPath"))
+		base := strings.TrimSpace(r.URL.Query().Get("base"))
+		head := strings.TrimSpace(r.URL.Query().Get("head"))
+
+		if path == "" {
+			http.Error(w, "path is required", 400)
+			return
+		}
+		if base == "" || head == "" {
+			http.Error(w, "base and head are required", 400)
+			return
+		}
+
+		items, err := GetGitAIFileNotes(repo.Path, base, head, path, oldPath)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": items,
+		})
+	})
+
+	// API: return Git AI prompt details for a commit/prompt id.
+	mux.HandleFunc("/api/git-ai/prompt", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		promptID := strings.TrimSpace(r.URL.Query().Get("promptId"))
+		commit := strings.TrimSpace(r.URL.Query().Get("commit"))
+		if promptID == "" {
+			http.Error(w, "promptId is required", 400)
+			return
+		}
+		if commit == "" {
+			http.Error(w, "commit is required", 400)
+			return
+		}
+
+		detail, err := GetGitAIPromptDetail(repo.Path, promptID, commit)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(detail)
+	})
+
+	// API: return a line range from the old or new version of a file.
+	mux.HandleFunc("/api/file/lines", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+		cfg.RepoPath = repo.Path
+
+		query := r.URL.Query()
+		path := strings.TrimSpace(query.Get("path"))
+		if path == "" {
+			http.Error(w, "path is required", 400)
+			return
+		}
+		side := strings.TrimSpace(query.Get("side"))
+		if side == "" {
+			side = "new"
+		}
+		start, err := parseOptionalInt(query.Get("start"))
+		if err != nil {
+			http.Error(w, "start must be a number", 400)
+			return
+		}
+		end, err := parseOptionalInt(query.Get("end"))
+		if err != nil {
+			http.Error(w, "end must be a number", 400)
+			return
+		}
+
+		result, err := GetFileLines(cfg, path, strings.TrimSpace(query.Get("oldPath")), side, start, end)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+
+	// API: return the whole new version of a file with the diff's changes marked.
+	mux.HandleFunc("/api/file/full", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+		cfg.RepoPath = repo.Path
+
+		file := findDiffFile(holder.Get(), r.URL.Query().Get("path"))
+		if file == nil {
+			http.Error(w, "File not found in current diff", 404)
+			return
+		}
+
+		result, err := GetFullFile(cfg, file)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+
+	// API: ask the AI for a patch that addresses a finding or checklist item.
+	mux.HandleFunc("/api/ai/fix/suggest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		if ai == nil {
+			http.Error(w, "No AI provider configured", 400)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req struct {
+			Path  string `json:"path"`
+			Issue string `json:"issue"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Issue) == "" {
+			http.Error(w, "Issue is required", 400)
+			return
+		}
+
+		file := findDiffFile(holder.Get(), req.Path)
+		if file == nil {
+			http.Error(w, "File not found in current diff", 404)
+			return
+		}
+
+		suggestion, err := SuggestFix(repo.Path, file, req.Issue, ai)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(suggestion)
+	})
+
+	// API: apply a previously suggested patch to the working tree.
+	mux.HandleFunc("/api/ai/fix/apply", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req struct {
+			Path    string `json:"path"`
+			Patch   string `json:"patch"`
+			Confirm bool   `json:"confirm"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			http.Error(w, "Path is required", 400)
+			return
+		}
+		if !req.Confirm {
+			http.Error(w, "Applying a patch requires explicit confirmation", 400)
+			return
+		}
+
+		if err := ApplyFix(repo.Path, req.Path, req.Patch); err != nil {
+			http.Error(w, err.Error(), 409)
+			return
+		}
+
+		if err := reloadCurrentRepo(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+	})
+
+	// API: stage a full file path.
+	mux.HandleFunc("/api/git/stage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req struct {
+			Path string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			http.Error(w, "Path is required", 400)
+			return
+		}
+
+		if err := StageFile(repo.Path, req.Path); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		if err := reloadCurrentRepo(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+	})
+
+	// API: unstage a full file path.
+	mux.HandleFunc("/api/git/unstage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req struct {
+			Path string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			http.Error(w, "Path is required", 400)
+			return
+		}
+
+		if err := UnstageFile(repo.Path, req.Path); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		if err := reloadCurrentRepo(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+	})
+
+	// API: discard all staged/unstaged changes for a file path.
+	mux.HandleFunc("/api/git/discard", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req struct {
+			Path string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", 400)
+			return
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			http.Error(w, "Path is required", 400)
+			return
+		}
+
+		if err := DiscardFileChanges(repo.Path, req.Path); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		if err := reloadCurrentRepo(); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload diff: %v", err), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildDiffResponse(holder.Get()))
+	})
+
+	// API: commit all staged changes and push.
+	mux.HandleFunc("/api/git/commit-push", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", 405)
+			return
+		}
+
+		repo, ok := repos.Current()
+		if !ok {
+			http.Error(w, "No repository selected", 400)
+			return
+		}
+
+		var req struct {
 			Message string `json:"message"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -645,6 +1548,10 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 			http.Error(w, err.Error(), 500)
 			return
 		}
+		if status.Operation != "" {
+			http.Error(w, fmt.Sprintf("A %s is in progress; resolve or abort it first", status.Operation), 409)
+			return
+		}
 		if len(status.StagedFiles) == 0 {
 			http.Error(w, "No staged files to commit", 400)
 			return
@@ -658,6 +1565,13 @@ func RegisterHandlers(mux *http.ServeMux, cfg *Config, holder *DiffHolder, repos
 
 		syncResult, err := SyncWithRemote(repo.Path)
 		if err != nil {
+			// A conflicting pull --rebase stops mid-rebase; hand it over to
+			// the conflict endpoints instead of leaving it unexplained
+			if operation := DetectOperation(repo.Path); operation != "" {
+				_ = reloadCurrentRepo()
+				http.Error(w, fmt.Sprintf("Committed, but syncing with the remote stopped with conflicts during a %s. Resolve them and continue, or abort, before pushing.\n%s", operation, syncResult.Output), 409)
+				return
+			}
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -720,14 +1634,63 @@ func spaHandler(fsys fs.FS) http.Handler {
 	})
 }
 
+func validateRangeDiffSpec(spec RangeDiffSpec) error {
+	if strings.TrimSpace(spec.OldBase) == "" || strings.TrimSpace(spec.OldHead) == "" ||
+		strings.TrimSpace(spec.NewBase) == "" || strings.TrimSpace(spec.NewHead) == "" {
+		return fmt.Errorf("oldBase, oldHead, newBase and newHead are required")
+	}
+	return nil
+}
+
+// parseOptionalInt parses a query parameter, treating an empty value as 0.
+func parseOptionalInt(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// findDiffFile returns the file with the given path from the current diff, if any.
+func findDiffFile(data *DiffData, path string) *DiffFile {
+	path = strings.TrimSpace(path)
+	if data == nil || path == "" {
+		return nil
+	}
+	for _, f := range data.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// DiffFileSummary is a file's metadata without its hunks or raw diff.
+// The shadowing fields drop the heavy content from the embedded file's JSON.
+type DiffFileSummary struct {
+	*DiffFile
+	Hunks     []*DiffHunk `json:"hunks,omitempty"`
+	RawDiff   string      `json:"rawDiff,omitempty"`
+	HunkCount int         `json:"hunkCount"`
+}
+
+func summarizeDiffFile(f *DiffFile) DiffFileSummary {
+	return DiffFileSummary{DiffFile: f, HunkCount: len(f.Hunks)}
+}
+
 // computeStats calculates aggregate statistics about the diff.
 func computeStats(data *DiffData) map[string]interface{} {
 	if data == nil {
 		return map[string]interface{}{
-			"totalFiles":   0,
-			"totalAdded":   0,
-			"totalRemoved": 0,
-			"groupCounts":  map[string]int{},
+			"totalFiles":     0,
+			"totalAdded":     0,
+			"totalRemoved":   0,
+			"excludedFiles":  0,
+			"ignoredFiles":   0,
+			"collapsedFiles": 0,
+			"tooLargeFiles":  0,
+			"secretFiles":    0,
+			"groupCounts":    map[string]int{},
 			"riskDistribution": map[string]int{
 				"high":   0,
 				"medium": 0,
@@ -738,6 +1701,8 @@ func computeStats(data *DiffData) map[string]interface{} {
 
 	totalAdded := 0
 	totalRemoved := 0
+	tooLarge := 0
+	secretFiles := 0
 	groupCounts := make(map[string]int)
 	riskDistribution := map[string]int{
 		"high":   0, // 50+
@@ -749,6 +1714,12 @@ func computeStats(data *DiffData) map[string]interface{} {
 		totalAdded += f.LinesAdded
 		totalRemoved += f.LinesRemoved
 		groupCounts[f.SemanticGroup]++
+		if f.TooLarge {
+			tooLarge++
+		}
+		if len(f.Secrets) > 0 {
+			secretFiles++
+		}
 
 		switch {
 		case f.RiskScore >= 50:
@@ -764,6 +1735,11 @@ func computeStats(data *DiffData) map[string]interface{} {
 		"totalFiles":       len(data.Files),
 		"totalAdded":       totalAdded,
 		"totalRemoved":     totalRemoved,
+		"excludedFiles":    data.Filtered.Excluded,
+		"ignoredFiles":     data.Filtered.Ignored,
+		"collapsedFiles":   data.Filtered.Collapsed,
+		"tooLargeFiles":    tooLarge,
+		"secretFiles":      secretFiles,
 		"groupCounts":      groupCounts,
 		"riskDistribution": riskDistribution,
 	}
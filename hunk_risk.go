@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Hunk size thresholds for the hunk-level size signal.
+const (
+	largeHunkLines    = 100
+	sizeableHunkLines = 40
+)
+
+// maxSpreadBonus caps what other risky hunks add to a file's score.
+const maxSpreadBonus = 15
+
+// HotHunk is one hunk in the cross-file list of the riskiest hunks.
+type HotHunk struct {
+	Path          string   `json:"path"`
+	HunkIndex     int      `json:"hunkIndex"` // Index into the file's hunks
+	Header        string   `json:"header"`
+	NewStart      int      `json:"newStart"`
+	LinesAdded    int      `json:"linesAdded"`
+	LinesRemoved  int      `json:"linesRemoved"`
+	RiskScore     int      `json:"riskScore"`
+	RiskReasons   []string `json:"riskReasons"`
+	FileRiskScore int      `json:"fileRiskScore"`
+}
+
+// scoreHunksHeuristic scores each hunk of file from its path rules and the
+// line rules that match the hunk's changed lines. It returns the line-rule
+// score the file inherits: the positive rules of its riskiest hunk, plus 5 for
+// every other hunk that matched a rule, plus each negative rule that matched
+// any hunk, counted once. Only the reasons of rules that count are returned;
+// the evidence covers all hunks.
+func scoreHunksHeuristic(file *DiffFile, rules *RiskRuleSet, path []riskMatch) (int, []string, []RiskEvidence) {
+	pathScore := 0
+	var pathReasons []string
+	pathRules := map[string]bool{}
+	for _, m := range path {
+		pathScore += m.rule.Score
+		pathReasons = append(pathReasons, m.rule.Reason)
+		pathRules[m.rule.ID] = true
+	}
+
+	var reasons, negReasons []string
+	var evidence []RiskEvidence
+	best, negScore, risky, scored := 0, 0, 0, false
+	negRules := map[string]bool{}
+
+	// Generated code is reviewed at its source, and the lines of an LFS
+	// pointer say nothing; their hunks stay unscored
+	lines := !file.Generated && file.LFS == nil
+
+	for _, h := range file.Hunks {
+		h.RiskScore, h.RiskReasons = 0, nil
+		if !lines {
+			continue
+		}
+
+		ruleScore, posScore := 0, 0
+		var ruleReasons, posReasons []string
+		for _, m := range rules.matchLineRules(file, h) {
+			for _, ev := range m.evidence {
+				evidence = mergeEvidence(evidence, ev)
+			}
+			if pathRules[m.rule.ID] {
+				continue // Already counted for the path
+			}
+			ruleScore += m.rule.Score
+			ruleReasons = append(ruleReasons, m.rule.Reason)
+			if m.rule.Score >= 0 {
+				posScore += m.rule.Score
+				posReasons = append(posReasons, m.rule.Reason)
+			} else if !negRules[m.rule.ID] {
+				// Lowers the file score however many hunks it matches, so
+				// it is not lost when another hunk is the riskiest
+				negRules[m.rule.ID] = true
+				negScore += m.rule.Score
+				negReasons = append(negReasons, m.rule.Reason)
+			}
+		}
+		hunkReasons := append(append([]string{}, pathReasons...), ruleReasons...)
+
+		if !scored || posScore > best {
+			best, reasons, scored = posScore, posReasons, true
+		}
+		if ruleScore > 0 {
+			risky++
+		}
+
+		score := pathScore + ruleScore
+		changed := h.LinesAdded + h.LinesRemoved
+		if changed > largeHunkLines {
+			score += 10
+			hunkReasons = append(hunkReasons, "Large hunk (100+ lines)")
+		} else if changed > sizeableHunkLines {
+			score += 5
+			hunkReasons = append(hunkReasons, "Sizeable hunk (40+ lines)")
+		}
+		if h.LinesRemoved > h.LinesAdded*2 && h.LinesRemoved > 10 {
+			score += 10
+			hunkReasons = append(hunkReasons, "Significant code removal")
+		}
+		h.RiskScore = clampRiskScore(score)
+		h.RiskReasons = hunkReasons
+	}
+
+	if risky > 1 {
+		spread := (risky - 1) * 5
+		if spread > maxSpreadBonus {
+			spread = maxSpreadBonus
+		}
+		best += spread
+		reasons = append(reasons, fmt.Sprintf("Risky changes spread across %d hunks", risky))
+	}
+	return best + negScore, append(reasons, negReasons...), evidence
+}
+
+// HottestHunks returns the riskiest hunks across all files, highest first.
+// Collapsed, generated and LFS files and hunks without risk are left out.
+func HottestHunks(data *DiffData, limit int) []HotHunk {
+	hot := []HotHunk{}
+	if data == nil {
+		return hot
+	}
+	for _, f := range data.Files {
+		if f.Collapsed || f.Generated || f.LFS != nil {
+			continue
+		}
+		for i, h := range f.Hunks {
+			if h.RiskScore <= 0 {
+				continue
+			}
+			hot = append(hot, HotHunk{
+				Path:          f.Path,
+				HunkIndex:     i,
+				Header:        h.Header,
+				NewStart:      h.NewStart,
+				LinesAdded:    h.LinesAdded,
+				LinesRemoved:  h.LinesRemoved,
+				RiskScore:     h.RiskScore,
+				RiskReasons:   h.RiskReasons,
+				FileRiskScore: f.RiskScore,
+			})
+		}
+	}
+
+	// Ties go to the riskier file, then to diff order
+	sort.SliceStable(hot, func(i, j int) bool {
+		if hot[i].RiskScore != hot[j].RiskScore {
+			return hot[i].RiskScore > hot[j].RiskScore
+		}
+		return hot[i].FileRiskScore > hot[j].FileRiskScore
+	})
+	if limit > 0 && len(hot) > limit {
+		hot = hot[:limit]
+	}
+	return hot
+}
+
+func clampRiskScore(score int) int {
+	if score > 100 {
+		return 100
+	}
+	if score < 0 {
+		return 0
+	}
+	return score
+}
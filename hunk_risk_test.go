@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testRiskRuleSet(t *testing.T, rules ...RiskRule) *RiskRuleSet {
+	t.Helper()
+	set := &RiskRuleSet{}
+	for _, rule := range rules {
+		compiled, err := compileRiskRule(rule)
+		if err != nil {
+			t.Fatalf("compileRiskRule(%s): %v", rule.ID, err)
+		}
+		set.Rules = append(set.Rules, rule)
+		set.compiled = append(set.compiled, compiled)
+	}
+	return set
+}
+
+func TestScoreHunksHeuristic(t *testing.T) {
+	rules := testRiskRuleSet(t,
+		RiskRule{ID: "sql", Reason: "Contains raw SQL", Score: 20, Added: []string{`(?i)select `}},
+		RiskRule{ID: "exit", Reason: "Contains abrupt termination calls", Score: 15, Added: []string{`os\.Exit`}},
+		RiskRule{ID: "todo", Reason: "Adds a TODO", Score: 5, Added: []string{`TODO`}},
+	)
+
+	tests := []struct {
+		name        string
+		patch       string
+		wantScore   int
+		wantReasons []string
+		wantHunks   []int
+	}{
+		{
+			name: "one risky hunk",
+			patch: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,2 @@
+ package a
++var q = "SELECT * FROM t"
+`,
+			wantScore:   20,
+			wantReasons: []string{"Contains raw SQL"},
+			wantHunks:   []int{20},
+		},
+		{
+			name: "reasons come from the riskiest hunk only",
+			patch: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,2 @@
+ package a
++var q = "SELECT * FROM t"
+@@ -10,1 +11,2 @@
+ func f() {
++	os.Exit(1)
+`,
+			wantScore:   25,
+			wantReasons: []string{"Contains raw SQL", "Risky changes spread across 2 hunks"},
+			wantHunks:   []int{20, 15},
+		},
+		{
+			name: "rules in the same hunk add up",
+			patch: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,3 @@
+ package a
++// TODO: drop
++var q = "SELECT 1"
+@@ -10,1 +12,2 @@
+ func f() {
++	os.Exit(1)
+`,
+			wantScore:   30,
+			wantReasons: []string{"Contains raw SQL", "Adds a TODO", "Risky changes spread across 2 hunks"},
+			wantHunks:   []int{25, 15},
+		},
+		{
+			name: "spread bonus is capped",
+			patch: `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,2 @@
+ a
++TODO 1
+@@ -10,1 +11,2 @@
+ b
++TODO 2
+@@ -20,1 +22,2 @@
+ c
++TODO 3
+@@ -30,1 +33,2 @@
+ d
++TODO 4
+@@ -40,1 +44,2 @@
+ e
++TODO 5
+`,
+			wantScore:   5 + maxSpreadBonus,
+			wantReasons: []string{"Adds a TODO", "Risky changes spread across 5 hunks"},
+			wantHunks:   []int{5, 5, 5, 5, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := parseDiffOutput(tt.patch)
+			if len(files) != 1 {
+				t.Fatalf("parsed %d files", len(files))
+			}
+			file := files[0]
+			score, reasons, _ := scoreHunksHeuristic(file, rules, nil)
+			if score != tt.wantScore {
+				t.Errorf("score = %d, want %d", score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Errorf("reasons = %q, want %q", reasons, tt.wantReasons)
+			}
+			var hunks []int
+			for _, h := range file.Hunks {
+				hunks = append(hunks, h.RiskScore)
+			}
+			if !reflect.DeepEqual(hunks, tt.wantHunks) {
+				t.Errorf("hunk scores = %v, want %v", hunks, tt.wantHunks)
+			}
+		})
+	}
+}
+
+func TestScoreFileRiskNegativeLineRules(t *testing.T) {
+	rules := testRiskRuleSet(t,
+		RiskRule{ID: "api", Reason: "API", Score: 20, Paths: []string{"api/**"}},
+		RiskRule{ID: "log-only", Reason: "Only log lines", Score: -15, Added: []string{`^\s*log\.`}},
+		RiskRule{ID: "todo", Reason: "Adds a TODO", Score: 5, Added: []string{`TODO`}},
+	)
+	logHunk := "@@ -1,1 +1,2 @@\n func a() {\n+\tlog.Println(\"a\")\n"
+
+	tests := []struct {
+		name        string
+		hunks       []string
+		wantScore   int
+		wantReasons []string
+	}{
+		{
+			name:        "one hunk",
+			hunks:       []string{logHunk},
+			wantScore:   5,
+			wantReasons: []string{"API", "Only log lines"},
+		},
+		{
+			name:        "unrelated second hunk",
+			hunks:       []string{logHunk, "@@ -10,1 +11,2 @@\n func b() {\n+\tb()\n"},
+			wantScore:   5,
+			wantReasons: []string{"API", "Only log lines"},
+		},
+		{
+			name:        "riskier second hunk",
+			hunks:       []string{logHunk, "@@ -10,1 +11,2 @@\n func b() {\n+\t// TODO\n"},
+			wantScore:   10,
+			wantReasons: []string{"API", "Adds a TODO", "Only log lines"},
+		},
+		{
+			name:        "negative rule in every hunk counts once",
+			hunks:       []string{logHunk, "@@ -10,1 +11,2 @@\n func b() {\n+\tlog.Println(\"b\")\n"},
+			wantScore:   5,
+			wantReasons: []string{"API", "Only log lines"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := "diff --git a/api/a.go b/api/a.go\n--- a/api/a.go\n+++ b/api/a.go\n" + strings.Join(tt.hunks, "")
+			files := parseDiffOutput(patch)
+			if len(files) != 1 {
+				t.Fatalf("parsed %d files", len(files))
+			}
+			file := files[0]
+			scoreFileRiskHeuristic(file, rules)
+			if file.RiskScore != tt.wantScore {
+				t.Errorf("score = %d, want %d", file.RiskScore, tt.wantScore)
+			}
+			if !reflect.DeepEqual(file.RiskReasons, tt.wantReasons) {
+				t.Errorf("reasons = %q, want %q", file.RiskReasons, tt.wantReasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits that keep intra-line diffing cheap on huge or minified lines.
+const (
+	maxIntraLineRunes     = 1000  // Lines longer than this are not diffed word by word
+	maxIntraLineTokenCost = 40000 // Upper bound on tokensA*tokensB for the LCS table
+	maxIntraLineChanged   = 0.6   // Skip highlighting when most of the line changed
+	maxIntraLineFileLines = 5000  // Files with more changed lines are not diffed word by word
+)
+
+// LineSpan marks a changed region of a line as offsets [Start, End) into
+// DiffLine.Content, counted in UTF-16 code units as JavaScript indexes strings.
+type LineSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// computeIntraLineChanges pairs each run of removed lines with the run of added
+// lines that follows it and records the word-level spans that differ.
+func computeIntraLineChanges(hunk *DiffHunk) {
+	lines := hunk.Lines
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != "del" {
+			i++
+			continue
+		}
+
+		delStart := i
+		for i < len(lines) && lines[i].Kind == "del" {
+			i++
+		}
+		addStart := i
+		for i < len(lines) && lines[i].Kind == "add" {
+			i++
+		}
+
+		pairs := min(addStart-delStart, i-addStart)
+		for p := 0; p < pairs; p++ {
+			oldSpans, newSpans, ok := diffLineWords(lines[delStart+p].Content, lines[addStart+p].Content)
+			if !ok {
+				continue
+			}
+			lines[delStart+p].Changes = oldSpans
+			lines[addStart+p].Changes = newSpans
+		}
+	}
+}
+
+// diffLineWords returns the changed spans of two lines, or ok=false when the
+// lines are too long or too different for highlighting to help.
+func diffLineWords(oldLine string, newLine string) ([]LineSpan, []LineSpan, bool) {
+	if utf8.RuneCountInString(oldLine) > maxIntraLineRunes || utf8.RuneCountInString(newLine) > maxIntraLineRunes {
+		return nil, nil, false
+	}
+
+	a := tokenizeLine(oldLine)
+	b := tokenizeLine(newLine)
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxIntraLineTokenCost {
+		return nil, nil, false
+	}
+
+	// Longest common subsequence over tokens
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].text == b[j].text {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	keepA := make([]bool, len(a))
+	keepB := make([]bool, len(b))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].text == b[j].text:
+			keepA[i], keepB[j] = true, true
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	oldSpans, oldChanged := changedSpans(a, keepA)
+	newSpans, newChanged := changedSpans(b, keepB)
+	if len(oldSpans) == 0 && len(newSpans) == 0 {
+		return nil, nil, false
+	}
+
+	totalRunes := utf8.RuneCountInString(oldLine) + utf8.RuneCountInString(newLine)
+	if float64(oldChanged+newChanged) > maxIntraLineChanged*float64(totalRunes) {
+		return nil, nil, false
+	}
+
+	return oldSpans, newSpans, true
+}
+
+type lineToken struct {
+	text  string
+	start int // UTF-16 offset
+	end   int
+	runes int
+}
+
+// tokenizeLine splits a line into words, whitespace runs and single punctuation characters.
+func tokenizeLine(line string) []lineToken {
+	var tokens []lineToken
+	runes := []rune(line)
+
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		default:
+			return 3
+		}
+	}
+
+	// Characters outside the Basic Multilingual Plane take two UTF-16 units
+	units := func(r rune) int {
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	}
+
+	offset := 0
+	for i := 0; i < len(runes); {
+		start, startOffset := i, offset
+		c := class(runes[i])
+		offset += units(runes[i])
+		i++
+		if c != 3 {
+			for i < len(runes) && class(runes[i]) == c {
+				offset += units(runes[i])
+				i++
+			}
+		}
+		tokens = append(tokens, lineToken{text: string(runes[start:i]), start: startOffset, end: offset, runes: i - start})
+	}
+
+	return tokens
+}
+
+// changedSpans merges adjacent unkept tokens into spans and returns the number of changed runes.
+func changedSpans(tokens []lineToken, keep []bool) ([]LineSpan, int) {
+	var spans []LineSpan
+	changed := 0
+	for i, tok := range tokens {
+		if keep[i] {
+			continue
+		}
+		changed += tok.runes
+		if n := len(spans); n > 0 && spans[n-1].End == tok.start {
+			spans[n-1].End = tok.end
+			continue
+		}
+		spans = append(spans, LineSpan{Start: tok.start, End: tok.end})
+	}
+	return spans, changed
+}
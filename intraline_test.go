@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// spanTexts slices line at each span the way the frontend does, by UTF-16 offsets.
+func spanTexts(line string, spans []LineSpan) []string {
+	units := utf16.Encode([]rune(line))
+	var out []string
+	for _, s := range spans {
+		out = append(out, string(utf16.Decode(units[s.Start:s.End])))
+	}
+	return out
+}
+
+func TestDiffLineWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantOK  bool
+		wantOld []string
+		wantNew []string
+	}{
+		{
+			name:    "changed word",
+			old:     "return foo(a, b)",
+			new:     "return bar(a, b)",
+			wantOK:  true,
+			wantOld: []string{"foo"},
+			wantNew: []string{"bar"},
+		},
+		{
+			name:    "inserted argument",
+			old:     "call(a, b)",
+			new:     "call(a, x, b)",
+			wantOK:  true,
+			wantNew: []string{"x, "},
+		},
+		{
+			name:    "adjacent tokens merge into one span",
+			old:     "result = oldFunc()",
+			new:     "result = newFunc[]",
+			wantOK:  true,
+			wantOld: []string{"oldFunc()"},
+			wantNew: []string{"newFunc[]"},
+		},
+		{
+			name:    "offsets after an emoji",
+			old:     "msg := \"😀 hi\" + name",
+			new:     "msg := \"😀 hi\" + user",
+			wantOK:  true,
+			wantOld: []string{"name"},
+			wantNew: []string{"user"},
+		},
+		{
+			name:    "non-ASCII words",
+			old:     "naïve café = 1",
+			new:     "naïve café = 2",
+			wantOK:  true,
+			wantOld: []string{"1"},
+			wantNew: []string{"2"},
+		},
+		{name: "identical lines", old: "a = 1", new: "a = 1"},
+		{name: "mostly different", old: "alpha beta gamma", new: "one two three"},
+		{name: "empty line", old: "", new: "a"},
+		{name: "too long", old: strings.Repeat("a ", maxIntraLineRunes), new: strings.Repeat("b ", maxIntraLineRunes)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldSpans, newSpans, ok := diffLineWords(tt.old, tt.new)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v (spans %v %v)", ok, tt.wantOK, oldSpans, newSpans)
+			}
+			if !ok {
+				return
+			}
+			if got := spanTexts(tt.old, oldSpans); !reflect.DeepEqual(got, tt.wantOld) {
+				t.Errorf("old spans = %q, want %q", got, tt.wantOld)
+			}
+			if got := spanTexts(tt.new, newSpans); !reflect.DeepEqual(got, tt.wantNew) {
+				t.Errorf("new spans = %q, want %q", got, tt.wantNew)
+			}
+		})
+	}
+}
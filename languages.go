@@ -0,0 +1,191 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// languageDef describes how to recognize one language. Names are the ids
+// used in DiffFile.Language; aliases are the Linguist names accepted in
+// linguist-language attributes.
+type languageDef struct {
+	Name         string
+	Extensions   []string
+	Filenames    []string // Lowercase base names
+	Interpreters []string // Shebang interpreters, without version suffixes
+	Aliases      []string // Lowercase
+}
+
+var languageDefs = []languageDef{
+	{Name: "go", Extensions: []string{".go"}, Filenames: []string{"go.mod", "go.work"}, Aliases: []string{"golang"}},
+	{Name: "python", Extensions: []string{".py", ".pyi", ".pyw"}, Filenames: []string{"sconstruct", "sconscript"}, Interpreters: []string{"python", "pypy"}},
+	{Name: "javascript", Extensions: []string{".js", ".jsx", ".mjs", ".cjs"}, Interpreters: []string{"node", "nodejs", "bun"}, Aliases: []string{"js"}},
+	{Name: "typescript", Extensions: []string{".ts", ".tsx", ".mts", ".cts"}, Interpreters: []string{"deno", "ts-node", "tsx"}, Aliases: []string{"ts", "tsx"}},
+	{Name: "rust", Extensions: []string{".rs"}},
+	{Name: "ruby", Extensions: []string{".rb", ".rake", ".gemspec"}, Filenames: []string{"gemfile", "rakefile", "podfile", "vagrantfile", "brewfile", "guardfile"}, Interpreters: []string{"ruby"}},
+	{Name: "java", Extensions: []string{".java"}},
+	{Name: "kotlin", Extensions: []string{".kt", ".kts"}},
+	{Name: "scala", Extensions: []string{".scala", ".sc", ".sbt"}, Interpreters: []string{"scala"}},
+	{Name: "groovy", Extensions: []string{".groovy", ".gradle"}, Filenames: []string{"jenkinsfile"}, Interpreters: []string{"groovy"}, Aliases: []string{"gradle"}},
+	{Name: "swift", Extensions: []string{".swift"}},
+	{Name: "objective-c", Extensions: []string{".m", ".mm"}, Aliases: []string{"objective-c++", "objc"}},
+	{Name: "c", Extensions: []string{".c", ".h"}},
+	{Name: "cpp", Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}, Aliases: []string{"c++"}},
+	{Name: "csharp", Extensions: []string{".cs", ".csx"}, Aliases: []string{"c#"}},
+	{Name: "fsharp", Extensions: []string{".fs", ".fsx", ".fsi"}, Aliases: []string{"f#"}},
+	{Name: "php", Extensions: []string{".php"}, Interpreters: []string{"php"}},
+	{Name: "perl", Extensions: []string{".pl", ".pm"}, Interpreters: []string{"perl"}},
+	{Name: "lua", Extensions: []string{".lua"}, Interpreters: []string{"lua", "luajit"}},
+	{Name: "elixir", Extensions: []string{".ex", ".exs"}, Interpreters: []string{"elixir"}},
+	{Name: "erlang", Extensions: []string{".erl", ".hrl"}, Filenames: []string{"rebar.config"}, Interpreters: []string{"escript"}},
+	{Name: "haskell", Extensions: []string{".hs", ".lhs"}, Interpreters: []string{"runhaskell", "runghc"}},
+	{Name: "ocaml", Extensions: []string{".ml", ".mli"}, Interpreters: []string{"ocaml"}},
+	{Name: "clojure", Extensions: []string{".clj", ".cljs", ".cljc", ".edn"}},
+	{Name: "dart", Extensions: []string{".dart"}},
+	{Name: "r", Extensions: []string{".r"}, Interpreters: []string{"rscript"}},
+	{Name: "julia", Extensions: []string{".jl"}, Interpreters: []string{"julia"}},
+	{Name: "zig", Extensions: []string{".zig"}},
+	{Name: "nim", Extensions: []string{".nim"}},
+	{Name: "nix", Extensions: []string{".nix"}},
+	{Name: "vue", Extensions: []string{".vue"}},
+	{Name: "svelte", Extensions: []string{".svelte"}},
+	{Name: "sql", Extensions: []string{".sql"}},
+	{Name: "bash", Extensions: []string{".sh", ".bash", ".zsh", ".ksh"}, Filenames: []string{".bashrc", ".bash_profile", ".zshrc", ".profile", "pkgbuild"}, Interpreters: []string{"sh", "bash", "zsh", "dash", "ksh"}, Aliases: []string{"shell", "sh", "zsh"}},
+	{Name: "fish", Extensions: []string{".fish"}, Interpreters: []string{"fish"}},
+	{Name: "powershell", Extensions: []string{".ps1", ".psm1", ".psd1"}, Interpreters: []string{"pwsh", "powershell"}},
+	{Name: "yaml", Extensions: []string{".yaml", ".yml"}},
+	{Name: "json", Extensions: []string{".json", ".jsonc", ".json5"}, Filenames: []string{"flake.lock", ".babelrc", ".eslintrc"}},
+	{Name: "toml", Extensions: []string{".toml"}, Filenames: []string{"cargo.lock", "pipfile", "poetry.lock"}},
+	{Name: "xml", Extensions: []string{".xml", ".xsd", ".xsl", ".plist", ".csproj", ".svg"}},
+	{Name: "html", Extensions: []string{".html", ".htm"}},
+	{Name: "css", Extensions: []string{".css", ".scss", ".sass", ".less"}, Aliases: []string{"scss", "sass", "less"}},
+	{Name: "markdown", Extensions: []string{".md", ".markdown", ".mdx"}},
+	{Name: "protobuf", Extensions: []string{".proto"}, Aliases: []string{"protocol buffer"}},
+	{Name: "graphql", Extensions: []string{".graphql", ".gql"}},
+	{Name: "terraform", Extensions: []string{".tf", ".tfvars", ".hcl"}, Aliases: []string{"hcl"}},
+	{Name: "dockerfile", Extensions: []string{".dockerfile"}, Filenames: []string{"dockerfile", "containerfile"}},
+	{Name: "makefile", Extensions: []string{".mk", ".mak"}, Filenames: []string{"makefile", "gnumakefile"}, Aliases: []string{"make"}},
+	{Name: "cmake", Extensions: []string{".cmake"}, Filenames: []string{"cmakelists.txt"}},
+	{Name: "starlark", Extensions: []string{".bzl", ".star", ".bazel"}, Filenames: []string{"build", "workspace", "buck", "tiltfile"}, Aliases: []string{"bazel", "skylark"}},
+}
+
+var (
+	languageByExt         = map[string]string{}
+	languageByFilename    = map[string]string{}
+	languageByInterpreter = map[string]string{}
+	languageByAlias       = map[string]string{}
+
+	interpreterVersionRe = regexp.MustCompile(`[\d.]+$`)
+)
+
+// maxShebangBytes is how much of a file is read to find its shebang line.
+const maxShebangBytes = 256
+
+func init() {
+	for _, def := range languageDefs {
+		for _, ext := range def.Extensions {
+			languageByExt[ext] = def.Name
+		}
+		for _, name := range def.Filenames {
+			languageByFilename[name] = def.Name
+		}
+		for _, interp := range def.Interpreters {
+			languageByInterpreter[interp] = def.Name
+		}
+		languageByAlias[def.Name] = def.Name
+		for _, alias := range def.Aliases {
+			languageByAlias[alias] = def.Name
+		}
+	}
+}
+
+// detectLanguage guesses the language from the file name alone.
+func detectLanguage(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	if lang, ok := languageByFilename[base]; ok {
+		return lang
+	}
+	if lang, ok := languageByExt[strings.ToLower(filepath.Ext(path))]; ok {
+		return lang
+	}
+	// Variants such as Dockerfile.dev or Makefile.common
+	if prefix, _, ok := strings.Cut(base, "."); ok && strings.HasSuffix(prefix, "file") {
+		if lang, ok := languageByFilename[prefix]; ok {
+			return lang
+		}
+	}
+	return "plaintext"
+}
+
+// languageFromShebang maps a "#!" line to a language, looking through env.
+func languageFromShebang(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			// Skip env options such as -S and VAR=value assignments
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			interp = filepath.Base(f)
+			break
+		}
+	}
+	interp = interpreterVersionRe.ReplaceAllString(strings.ToLower(interp), "")
+	return languageByInterpreter[interp]
+}
+
+// normalizeLanguageName maps a Linguist language name to a language id.
+func normalizeLanguageName(name string) string {
+	// Attribute values can't hold spaces, so Linguist names use hyphens there
+	name = strings.ToLower(strings.TrimSpace(name))
+	if lang, ok := languageByAlias[name]; ok {
+		return lang
+	}
+	if lang, ok := languageByAlias[strings.ReplaceAll(name, "-", " ")]; ok {
+		return lang
+	}
+	return strings.ReplaceAll(name, " ", "-")
+}
+
+// refineFileLanguages applies linguist-language overrides and recognizes
+// extensionless scripts by their shebang line. The first line comes from the
+// hunks when they include it, otherwise from the first bytes of the file in src.
+func refineFileLanguages(repoPath string, src fileSource, files []*DiffFile, attrs map[string]linguistAttr) {
+	for _, file := range files {
+		if a, ok := attrs[file.Path]; ok && a.language != "" {
+			file.Language = normalizeLanguageName(a.language)
+			continue
+		}
+		// A shebang beats a guess from an extensionless name such as "build"
+		if filepath.Ext(file.Path) != "" || file.Status == "deleted" || file.Status == "binary" {
+			continue
+		}
+
+		firstLine, found := "", false
+		for _, h := range file.Hunks {
+			for _, line := range h.Lines {
+				if line.NewLine == 1 {
+					firstLine, found = line.Content, true
+				}
+			}
+		}
+		if !found && src.Kind != "" && repoPath != "" && !file.TooLarge {
+			if head, err := readFileHead(repoPath, src, file.Path, maxShebangBytes); err == nil {
+				firstLine, _, _ = strings.Cut(string(head), "\n")
+			}
+		}
+		if lang := languageFromShebang(firstLine); lang != "" {
+			file.Language = lang
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LFSChange describes a change to a Git LFS pointer file. The diff only shows
+// the pointer, so the object sizes are what tell how big the change really is.
+type LFSChange struct {
+	OldOID  string `json:"oldOid,omitempty"` // Empty when the old side is not an LFS pointer
+	NewOID  string `json:"newOid,omitempty"` // Empty when the new side is not an LFS pointer
+	OldSize int64  `json:"oldSize,omitempty"`
+	NewSize int64  `json:"newSize,omitempty"` // 0 when the size line is unchanged and outside the diff context
+}
+
+const (
+	lfsSpecVersion   = "https://git-lfs.github.com/spec/v1"
+	lfsLargeObject   = 100 << 20 // Objects this size or above are flagged as large
+	lfsSizeJumpBytes = 50 << 20  // Size changes of this much are flagged regardless of ratio
+)
+
+var lfsOIDRe = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// lfsPointer is one side of a pointer file as far as the hunks show it.
+type lfsPointer struct {
+	oid  string
+	size int64
+}
+
+// detectLFSPointer recognizes pointer files from the hunk lines and records
+// the objects they point to.
+func detectLFSPointer(file *DiffFile) {
+	if file.Status == "binary" || len(file.Hunks) == 0 {
+		return
+	}
+	var oldLines, newLines []string
+	for _, h := range file.Hunks {
+		for _, line := range h.Lines {
+			if line.Kind != "add" {
+				oldLines = append(oldLines, line.Content)
+			}
+			if line.Kind != "del" {
+				newLines = append(newLines, line.Content)
+			}
+		}
+	}
+
+	oldPtr, oldOK := parseLFSPointer(oldLines)
+	newPtr, newOK := parseLFSPointer(newLines)
+	if !oldOK && !newOK {
+		return
+	}
+
+	change := &LFSChange{}
+	if oldOK {
+		change.OldOID, change.OldSize = oldPtr.oid, oldPtr.size
+	}
+	if newOK {
+		change.NewOID, change.NewSize = newPtr.oid, newPtr.size
+	}
+	file.LFS = change
+}
+
+// parseLFSPointer reads pointer lines. With little diff context the version
+// line may be cut off, so an oid line is what identifies a pointer.
+func parseLFSPointer(lines []string) (lfsPointer, bool) {
+	var ptr lfsPointer
+	if len(lines) == 0 {
+		return ptr, false
+	}
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			return ptr, false
+		}
+		switch {
+		case key == "version":
+			if value != lfsSpecVersion {
+				return ptr, false
+			}
+		case key == "oid":
+			if !lfsOIDRe.MatchString(value) {
+				return ptr, false
+			}
+			ptr.oid = value
+		case key == "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return ptr, false
+			}
+			ptr.size = size
+		case strings.HasPrefix(key, "ext-"):
+		default:
+			return ptr, false
+		}
+	}
+	return ptr, ptr.oid != ""
+}
+
+// lfsRisk scores an LFS change by what happened to the object rather than by
+// the few pointer lines in the diff.
+func lfsRisk(file *DiffFile) (int, []string) {
+	lfs := file.LFS
+	score := 0
+	var reasons []string
+
+	switch {
+	case lfs.OldOID == "" && lfs.NewOID != "" && file.Status != "added":
+		score += 15
+		reasons = append(reasons, "Moves file into Git LFS")
+	case lfs.OldOID != "" && lfs.NewOID == "" && file.Status != "deleted":
+		score += 15
+		reasons = append(reasons, "Moves file out of Git LFS")
+	case file.Status == "added":
+		score += 10
+		reasons = append(reasons, "Adds Git LFS object"+lfsSizeSuffix(lfs.NewSize))
+	case file.Status == "deleted":
+		score += 5
+		reasons = append(reasons, "Removes Git LFS object"+lfsSizeSuffix(lfs.OldSize))
+	case lfs.OldOID != lfs.NewOID:
+		score += 10
+		reasons = append(reasons, "Replaces Git LFS object")
+		if lfs.OldSize > 0 && lfs.NewSize > 0 {
+			delta := lfs.NewSize - lfs.OldSize
+			if delta < 0 {
+				delta = -delta
+			}
+			if delta >= lfsSizeJumpBytes || lfs.NewSize >= 2*lfs.OldSize || 2*lfs.NewSize <= lfs.OldSize {
+				score += 15
+				reasons = append(reasons, fmt.Sprintf("LFS object size changes from %s to %s", formatBytes(lfs.OldSize), formatBytes(lfs.NewSize)))
+			}
+		}
+	}
+
+	if lfs.NewSize >= lfsLargeObject {
+		score += 10
+		reasons = append(reasons, "Large LFS object ("+formatBytes(lfs.NewSize)+")")
+	}
+	return score, reasons
+}
+
+// lfsDiffText stands in for the pointer text wherever a diff is sent to the AI.
+func lfsDiffText(file *DiffFile) string {
+	lfs := file.LFS
+	describe := func(oid string, size int64) string {
+		if oid == "" {
+			return "not stored in Git LFS"
+		}
+		if size == 0 {
+			return "Git LFS object of unchanged size"
+		}
+		return "Git LFS object of " + formatBytes(size)
+	}
+	switch file.Status {
+	case "added":
+		return "(Git LFS pointer added: " + describe(lfs.NewOID, lfs.NewSize) + "; content not shown)"
+	case "deleted":
+		return "(Git LFS pointer deleted: " + describe(lfs.OldOID, lfs.OldSize) + "; content not shown)"
+	}
+	return "(Git LFS pointer changed from " + describe(lfs.OldOID, lfs.OldSize) + " to " + describe(lfs.NewOID, lfs.NewSize) + "; content not shown)"
+}
+
+// aiDiffText is the diff of file as sent to the AI.
+func aiDiffText(file *DiffFile, maxLen int) string {
+	if file.LFS != nil {
+		return lfsDiffText(file)
+	}
+	return truncate(redactSecrets(file, file.RawDiff), maxLen)
+}
+
+// aiHunkedDiffText is the diff of file as sent to the AI, with each hunk
+// numbered so it can be scored on its own.
+func aiHunkedDiffText(file *DiffFile, maxLen int) string {
+	if file.LFS != nil || len(file.Hunks) == 0 {
+		return aiDiffText(file, maxLen)
+	}
+	var b strings.Builder
+	for i, h := range file.Hunks {
+		fmt.Fprintf(&b, "[hunk %d] %s\n%s", i, h.Header, h.Content)
+	}
+	return truncate(redactSecrets(file, b.String()), maxLen)
+}
+
+func lfsSizeSuffix(size int64) string {
+	if size == 0 {
+		return ""
+	}
+	return " (" + formatBytes(size) + ")"
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "412.3 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
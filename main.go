@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 //go:embed all:static
@@ -20,8 +21,14 @@ type Config struct {
 	RepoPath       string
 	Base           string
 	Head           string
+	Mode           string // Comparison mode: three-dot, two-dot, worktree, index
 	Staged         bool
 	Unstaged       bool
+	Commits        *CommitRange   // Review only these commits of base..head
+	RangeDiff      *RangeDiffSpec // Review what changed between two versions of a series
+	Stash          string         // Review this stash commit against its parent
+	Patch          *PatchImport   // Review an imported patch or mbox file instead of a git diff
+	Diff           DiffOptions    // Context, whitespace, rename/copy and algorithm options
 	Port           int
 	AIProvider     string // "none", "claude", "ollama", "lmstudio"
 	OllamaModel    string
@@ -30,6 +37,7 @@ type Config struct {
 	LMStudioURL    string
 	LMStudioAPIKey string
 	AnthropicKey   string
+	AIGenerated    bool   // Send generated/vendored files to the AI as well
 	Dev            bool   // Dev mode: proxy static files to Vite dev server
 	ViteURL        string // Vite dev server URL (default http://localhost:5173)
 }
@@ -97,7 +105,7 @@ func main() {
 	aiClient := NewAIClient(cfg)
 
 	var diffData *DiffData
-	if cfg.RepoPath != "" {
+	if cfg.RepoPath != "" || cfg.Patch != nil {
 		parsedDiff, err := ParseGitDiff(cfg)
 		if err != nil && !cfg.Staged && !cfg.Unstaged {
 			cfg.Base = ResolveDefaultBaseRef(cfg.RepoPath)
@@ -138,7 +146,10 @@ func main() {
 
 	addr := fmt.Sprintf("127.0.0.1:%d", cfg.Port)
 	fmt.Printf("\n  🧭 DiffDragon is running at http://%s\n", addr)
-	if cfg.RepoPath != "" {
+	if cfg.Patch != nil {
+		fmt.Printf("  📄 Patch: %s\n", cfg.Patch.Name)
+		fmt.Printf("  📊 Files changed: %d\n", len(diffData.Files))
+	} else if cfg.RepoPath != "" {
 		fmt.Printf("  📂 Repository: %s\n", cfg.RepoPath)
 		fmt.Printf("  📊 Files changed: %d\n", len(diffData.Files))
 	} else {
@@ -159,16 +170,33 @@ func main() {
 func parseFlags() *Config {
 	cfg := &Config{}
 	portExplicit := false
+	var patchFile string
+	var patchCheck bool
 
 	flag.StringVar(&cfg.RepoPath, "repo", "", "Optional initial git repository path")
 	flag.StringVar(&cfg.Base, "base", "main", "Base ref to diff against")
 	flag.StringVar(&cfg.Head, "head", "HEAD", "Head ref to diff")
+	flag.StringVar(&patchFile, "patch", "", "Review a .patch or mbox file instead of a git diff")
+	flag.BoolVar(&patchCheck, "patch-check", false, "With --patch, check whether each patch applies to HEAD of the repository")
+	flag.StringVar(&cfg.Mode, "mode", compareThreeDot, "Comparison: three-dot (base...head), two-dot (base..head), worktree (base vs working tree), index (base vs index)")
+	flag.IntVar(&cfg.Diff.ContextLines, "context", defaultContextLines, "Lines of context around each change")
+	flag.StringVar(&cfg.Diff.IgnoreWhitespace, "ignore-whitespace", "none", "Ignore whitespace: none, all (-w), change (-b), eol")
+	flag.BoolVar(&cfg.Diff.IgnoreBlankLines, "ignore-blank-lines", false, "Ignore changes whose lines are all blank")
+	flag.IntVar(&cfg.Diff.RenameThreshold, "rename-threshold", 0, "Similarity percent for rename detection (0 = git default)")
+	flag.IntVar(&cfg.Diff.CopyThreshold, "copy-threshold", 0, "Similarity percent for copy detection (0 = git default)")
+	flag.StringVar(&cfg.Diff.Algorithm, "diff-algorithm", "", "Diff algorithm: myers, minimal, patience, histogram")
+	flag.Var((*stringListFlag)(&cfg.Diff.Include), "include", "Only review paths matching this pathspec glob (repeatable)")
+	flag.Var((*stringListFlag)(&cfg.Diff.Exclude), "exclude", "Leave paths matching this pathspec glob out of the review (repeatable)")
+	flag.IntVar(&cfg.Diff.MaxFileBytes, "max-file-bytes", defaultMaxFileBytes, "Patch size above which a file is listed as too large")
+	flag.IntVar(&cfg.Diff.MaxFileLines, "max-file-lines", defaultMaxFileLines, "Patch lines above which a file is listed as too large")
+	flag.IntVar(&cfg.Diff.MaxTotalBytes, "max-diff-bytes", defaultMaxTotalBytes, "Total patch size loaded before remaining files are listed as too large")
 	flag.IntVar(&cfg.Port, "port", 8384, "Port for the local web server")
 	flag.StringVar(&cfg.AIProvider, "ai", "none", "AI provider: none, claude, ollama, lmstudio")
 	flag.StringVar(&cfg.OllamaModel, "ollama-model", "llama3.1", "Ollama model name")
 	flag.StringVar(&cfg.OllamaURL, "ollama-url", "http://localhost:11434", "Ollama API endpoint")
 	flag.StringVar(&cfg.LMStudioModel, "lmstudio-model", "local-model", "LM Studio model name")
 	flag.StringVar(&cfg.LMStudioURL, "lmstudio-url", "http://localhost:1234/v1", "LM Studio OpenAI-compatible endpoint")
+	flag.BoolVar(&cfg.AIGenerated, "ai-generated", false, "Include generated and vendored files in AI risk analysis")
 	flag.BoolVar(&cfg.Dev, "dev", false, "Dev mode: proxy static files to Vite dev server for HMR")
 	flag.StringVar(&cfg.ViteURL, "vite-url", "http://localhost:5173", "Vite dev server URL (used with --dev)")
 
@@ -183,5 +211,35 @@ func parseFlags() *Config {
 		cfg.Port = 8385
 	}
 
+	if err := validateCompareMode(cfg.Mode); err != nil {
+		log.Fatalf("Invalid --mode: %v", err)
+	}
+
+	cfg.Diff = normalizeDiffOptions(cfg.Diff)
+	if err := validateDiffOptions(cfg.Diff); err != nil {
+		log.Fatalf("Invalid diff options: %v", err)
+	}
+
+	if patchFile != "" {
+		imp, err := ReadPatchImport(patchFile)
+		if err != nil {
+			log.Fatalf("Invalid --patch: %v", err)
+		}
+		imp.Check = patchCheck
+		cfg.Patch = imp
+	}
+
 	return cfg
 }
+
+// stringListFlag collects a repeatable string flag.
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
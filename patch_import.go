@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PatchImport is a patch file or mbox series reviewed instead of a git diff.
+type PatchImport struct {
+	Name    string // File name shown in the UI
+	Content string
+	Index   int  // Patch of the series to review; -1 for all of them
+	Check   bool // Check whether the series applies to HEAD of the selected repository
+}
+
+// PatchSeries describes the patches of an imported file.
+type PatchSeries struct {
+	Name     string      `json:"name"`
+	Selected int         `json:"selected"` // -1 when the whole series is shown
+	Patches  []PatchInfo `json:"patches"`
+}
+
+// PatchInfo is one patch of an imported series.
+type PatchInfo struct {
+	Index      int    `json:"index"`
+	Subject    string `json:"subject"`
+	Author     string `json:"author,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Files      int    `json:"files"`
+	Applies    *bool  `json:"applies,omitempty"` // Set when checked against a repository
+	ApplyError string `json:"applyError,omitempty"`
+}
+
+// patchMessage is one mail of an mbox, or a whole plain patch file.
+type patchMessage struct {
+	Subject string
+	Author  string
+	Date    string
+	Diff    string
+}
+
+var (
+	mboxFromRe       = regexp.MustCompile(`^From \S+ +\w{3} \w{3} +\d+ [\d:]+ \d{4}`)
+	patchSubjectRe   = regexp.MustCompile(`^\[[^\]]*PATCH[^\]]*\]\s*`)
+	patchSignatureRe = regexp.MustCompile(`\n-- \n[^\n]*\n*$`)
+)
+
+// ReadPatchImport reads a patch or mbox file from disk.
+func ReadPatchImport(path string) (*PatchImport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch file: %w", err)
+	}
+	return &PatchImport{Name: path, Content: string(data), Index: -1}, nil
+}
+
+// resolvePatchPath resolves a patch path sent to the API, relative to the
+// repository or absolute, and refuses files outside the repository. Symlinks
+// are followed first so a link cannot point elsewhere.
+func resolvePatchPath(repoPath string, path string) (string, error) {
+	if repoPath == "" {
+		return "", fmt.Errorf("select a repository to load a patch file from it")
+	}
+	root, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repository path: %w", err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repository path: %w", err)
+	}
+
+	fullPath := filepath.FromSlash(path)
+	if !filepath.IsAbs(fullPath) {
+		fullPath = filepath.Join(root, fullPath)
+	}
+	resolved, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read patch file: %w", err)
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to read patch file: %w", err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("patch file %q is outside the repository", path)
+	}
+	return resolved, nil
+}
+
+// ParsePatchImport parses each patch of an imported file with the regular diff
+// parser and runs the same post-processing as a git diff.
+func ParsePatchImport(cfg *Config) (*DiffData, error) {
+	imp := cfg.Patch
+	messages := splitPatchSeries(imp.Content)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no patches found in %s", imp.Name)
+	}
+	if imp.Index >= len(messages) {
+		return nil, fmt.Errorf("patch %d out of range (series has %d)", imp.Index, len(messages))
+	}
+
+	series := &PatchSeries{Name: imp.Name, Selected: imp.Index, Patches: []PatchInfo{}}
+	files := []*DiffFile{}
+	byPath := map[string]*DiffFile{}
+	for i, msg := range messages {
+		parsed := parseDiffOutput(msg.Diff)
+		series.Patches = append(series.Patches, PatchInfo{
+			Index:   i,
+			Subject: msg.Subject,
+			Author:  msg.Author,
+			Date:    msg.Date,
+			Files:   len(parsed),
+		})
+		if imp.Index >= 0 && imp.Index != i {
+			continue
+		}
+		// Later patches to the same file are appended in series order
+		for _, f := range parsed {
+			if existing, ok := byPath[f.Path]; ok {
+				mergeFileDiff(existing, f)
+				continue
+			}
+			byPath[f.Path] = f
+			files = append(files, f)
+		}
+	}
+
+	if imp.Check && cfg.RepoPath != "" {
+		checkPatchSeries(cfg.RepoPath, messages, series)
+	}
+
+	data := &DiffData{
+		BaseRef: "patch",
+		HeadRef: imp.Name,
+		Options: cfg.Diff,
+		Patch:   series,
+		Files:   files,
+	}
+	if len(messages) > 1 && imp.Index < 0 {
+		data.Notice = "Files changed by several patches of the series show the hunks of each patch in order."
+	}
+
+	if cfg.RepoPath != "" {
+		rules, err := loadIgnoreRules(cfg.RepoPath)
+		if err != nil {
+			return nil, err
+		}
+		applyIgnoreRules(data, rules)
+	}
+	attrs := linguistAttributes(cfg.RepoPath, data.Files)
+	refineFileLanguages("", fileSource{}, data.Files, attrs)
+	markGeneratedFiles(data.Files, attrs)
+	data.setRiskRules(LoadRiskRules(cfg.RepoPath))
+
+	return data, nil
+}
+
+// splitPatchSeries splits an mbox into its mails. Content without mbox
+// separators is a single patch.
+func splitPatchSeries(content string) []patchMessage {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var raw []string
+	var current []string
+	for _, line := range strings.Split(content, "\n") {
+		if mboxFromRe.MatchString(line) && len(current) > 0 {
+			raw = append(raw, strings.Join(current, "\n"))
+			current = nil
+		}
+		current = append(current, line)
+	}
+	raw = append(raw, strings.Join(current, "\n"))
+
+	messages := []patchMessage{}
+	for _, text := range raw {
+		msg := parsePatchMessage(text)
+		if msg.Diff != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// parsePatchMessage extracts the mail headers and the diff from one patch.
+func parsePatchMessage(text string) patchMessage {
+	var msg patchMessage
+	lines := strings.Split(text, "\n")
+
+	// Mail headers run up to the first blank line; continuation lines start with whitespace
+	start := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "From ") {
+		start = 1
+	}
+	var lastHeader *string
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			break
+		}
+		if (line[0] == ' ' || line[0] == '\t') && lastHeader != nil {
+			*lastHeader += " " + strings.TrimSpace(line)
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			break // Not a mail; a plain diff
+		}
+		lastHeader = nil
+		switch strings.ToLower(name) {
+		case "subject":
+			msg.Subject = strings.TrimSpace(value)
+			lastHeader = &msg.Subject
+		case "from":
+			msg.Author = strings.TrimSpace(value)
+			lastHeader = &msg.Author
+		case "date":
+			msg.Date = strings.TrimSpace(value)
+		}
+	}
+
+	decoder := new(mime.WordDecoder)
+	if decoded, err := decoder.DecodeHeader(msg.Subject); err == nil {
+		msg.Subject = decoded
+	}
+	if decoded, err := decoder.DecodeHeader(msg.Author); err == nil {
+		msg.Author = decoded
+	}
+	msg.Subject = patchSubjectRe.ReplaceAllString(msg.Subject, "")
+
+	body := strings.Join(lines, "\n")
+	if strings.HasPrefix(body, "diff --git ") {
+		msg.Diff = body
+	} else if i := strings.Index(body, "\ndiff --git "); i >= 0 {
+		msg.Diff = body[i+1:]
+	} else {
+		msg.Diff = gitHeadersForPlainDiff(lines)
+	}
+	// format-patch ends each mail with a "-- " signature line and the git version
+	msg.Diff = patchSignatureRe.ReplaceAllString(msg.Diff, "\n")
+	return msg
+}
+
+// gitHeadersForPlainDiff turns a `diff -u` style patch into git's format by
+// adding a diff --git line to each file, so the regular parser can read it.
+func gitHeadersForPlainDiff(lines []string) string {
+	var out strings.Builder
+	found := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") &&
+			i+2 < len(lines) && strings.HasPrefix(lines[i+2], "@@") {
+			oldPath, oldMissing := plainDiffPath(strings.TrimPrefix(line, "--- "))
+			newPath, newMissing := plainDiffPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			// `diff -ru old new` names both sides after their directory; drop it like -p1
+			oldDir, oldRest, oldOK := strings.Cut(oldPath, "/")
+			newDir, newRest, newOK := strings.Cut(newPath, "/")
+			if oldOK && newOK && oldDir != newDir && oldRest == newRest {
+				oldPath, newPath = oldRest, newRest
+			}
+
+			path := newPath
+			if newMissing {
+				path = oldPath
+			}
+			if oldMissing && newMissing {
+				continue
+			}
+			out.WriteString("diff --git a/" + path + " b/" + path + "\n")
+			switch {
+			case oldMissing:
+				out.WriteString("new file mode 100644\n--- /dev/null\n+++ b/" + path + "\n")
+			case newMissing:
+				out.WriteString("deleted file mode 100644\n--- a/" + path + "\n+++ /dev/null\n")
+			default:
+				out.WriteString("--- a/" + oldPath + "\n+++ b/" + newPath + "\n")
+			}
+			i++
+			found = true
+			continue
+		}
+		// Command lines and notes between files, e.g. "diff -ruN a b" or "Only in a: x"
+		if strings.HasPrefix(line, "diff ") || strings.HasPrefix(line, "Only in ") || strings.HasPrefix(line, "Index: ") || strings.HasPrefix(line, "====") {
+			continue
+		}
+		if found {
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.String()
+}
+
+// plainDiffPath splits a ---/+++ value into its path, without the timestamp
+// or a leading a/ or b/, and whether the file is missing on that side.
+// `diff -N` marks a missing file with the epoch as its timestamp.
+func plainDiffPath(value string) (string, bool) {
+	path, stamp, _ := strings.Cut(value, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return path, true
+	}
+	return stripDiffPrefix(path), strings.HasPrefix(stamp, "1970-01-01 00:00:00")
+}
+
+// checkPatchSeries applies the series patch by patch to a scratch index of
+// HEAD, so each patch is checked on top of the ones before it.
+func checkPatchSeries(repoPath string, messages []patchMessage, series *PatchSeries) {
+	err := withScratchIndex(func(env []string) error {
+		if _, err := gitStdout(repoPath, env, "", "read-tree", "HEAD"); err != nil {
+			return err
+		}
+		for i, msg := range messages {
+			_, err := gitStdout(repoPath, env, msg.Diff, "apply", "--cached", "--check", "-")
+			if err == nil {
+				_, err = gitStdout(repoPath, env, msg.Diff, "apply", "--cached", "-")
+			}
+			ok := err == nil
+			series.Patches[i].Applies = &ok
+			if err != nil {
+				// Keep git's explanation, not the command line
+				_, detail, _ := strings.Cut(err.Error(), "\n")
+				series.Patches[i].ApplyError = detail
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range series.Patches {
+			series.Patches[i].ApplyError = err.Error()
+		}
+	}
+}
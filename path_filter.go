@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName is the repo-root file listing paths to drop or collapse before analysis.
+const ignoreFileName = ".diffdragonignore"
+
+// FilterStats counts files hidden from the review by path filters.
+type FilterStats struct {
+	Excluded  int `json:"excluded"`  // Hidden by include/exclude pathspecs
+	Ignored   int `json:"ignored"`   // Dropped by .diffdragonignore
+	Collapsed int `json:"collapsed"` // Listed without hunks by .diffdragonignore
+}
+
+// ignoreRule is one line of .diffdragonignore.
+//
+//	# comment
+//	package-lock.json      drop the file from the review
+//	vendor/                drop everything under a directory
+//	collapse: *.pb.go      keep the file listed but hide its hunks
+//	!vendor/keep.go        re-include a path matched by an earlier rule
+type ignoreRule struct {
+	pattern  *regexp.Regexp
+	collapse bool
+	negate   bool
+}
+
+// diffPathspecs turns include/exclude globs into git pathspecs.
+func diffPathspecs(opts DiffOptions) []string {
+	var specs []string
+	for _, p := range opts.Include {
+		if p = strings.TrimSpace(p); p != "" {
+			specs = append(specs, p)
+		}
+	}
+	for _, p := range opts.Exclude {
+		if p = strings.TrimSpace(p); p != "" {
+			specs = append(specs, ":(exclude)"+p)
+		}
+	}
+	return specs
+}
+
+// withPathspecs appends pathspecs after a "--" separator when there are any.
+func withPathspecs(args []string, specs []string) []string {
+	if len(specs) == 0 {
+		return args
+	}
+	args = append(args, "--")
+	return append(args, specs...)
+}
+
+// countPathspecExcluded returns how many changed paths the include/exclude
+// pathspecs hid, using cheap name-only listings.
+func countPathspecExcluded(cfg *Config, cmp diffComparison) (int, error) {
+	specs := diffPathspecs(cfg.Diff)
+	if len(specs) == 0 {
+		return 0, nil
+	}
+
+	count := func(specs []string) (int, error) {
+		args := append([]string{"diff"}, cmp.Args...)
+		args = append(args, "--name-only", "-z", "--no-renames")
+
+		out, err := runGitCommand(cfg.RepoPath, withPathspecs(args, specs)...)
+		if err != nil {
+			return 0, err
+		}
+		total := len(splitGitNulls(out))
+
+		if cmp.Untracked {
+			untracked, err := runGitCommand(cfg.RepoPath, withPathspecs([]string{"ls-files", "-z", "--others", "--exclude-standard"}, specs)...)
+			if err != nil {
+				return 0, err
+			}
+			total += len(splitGitNulls(untracked))
+		}
+		return total, nil
+	}
+
+	all, err := count(nil)
+	if err != nil {
+		return 0, err
+	}
+	kept, err := count(specs)
+	if err != nil {
+		return 0, err
+	}
+	if all < kept {
+		return 0, nil
+	}
+	return all - kept, nil
+}
+
+// loadIgnoreRules reads .diffdragonignore from the repository root. A missing file yields no rules.
+func loadIgnoreRules(repoPath string) ([]ignoreRule, error) {
+	f, err := os.Open(filepath.Join(repoPath, ignoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", ignoreFileName, err)
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = strings.TrimSpace(line[1:])
+		}
+		if strings.HasPrefix(line, "collapse:") {
+			rule.collapse = true
+			line = strings.TrimSpace(strings.TrimPrefix(line, "collapse:"))
+		}
+		if line == "" {
+			return nil, fmt.Errorf("%s:%d: empty pattern", ignoreFileName, lineNo)
+		}
+
+		rule.pattern = compilePathGlob(line)
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ignoreFileName, err)
+	}
+
+	return rules, nil
+}
+
+// compilePathGlob converts a gitignore-style glob into a regexp matched against
+// repo-relative paths. Patterns without a slash match a name at any depth, a
+// leading slash anchors to the root, a trailing slash matches a directory and
+// everything below it, and "**" spans directories.
+func compilePathGlob(pattern string) *regexp.Regexp {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				// "**/" matches zero or more directories
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*")
+	} else {
+		// A pattern naming a directory also matches the files below it
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+// ignoreAction returns "drop", "collapse" or "" for a path; the last matching rule wins.
+func ignoreAction(rules []ignoreRule, path string) string {
+	action := ""
+	for _, rule := range rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		switch {
+		case rule.negate:
+			action = ""
+		case rule.collapse:
+			action = "collapse"
+		default:
+			action = "drop"
+		}
+	}
+	return action
+}
+
+// applyIgnoreRules drops or collapses files matched by .diffdragonignore.
+// Collapsed files keep their stats but lose hunks, so neither the UI nor the
+// AI has to wade through them.
+func applyIgnoreRules(data *DiffData, rules []ignoreRule) {
+	if len(rules) == 0 {
+		return
+	}
+
+	kept := make([]*DiffFile, 0, len(data.Files))
+	for _, file := range data.Files {
+		action := ignoreAction(rules, file.Path)
+		if action == "" && file.OldPath != "" {
+			action = ignoreAction(rules, file.OldPath)
+		}
+
+		switch action {
+		case "drop":
+			data.Filtered.Ignored++
+			continue
+		case "collapse":
+			collapseFile(file)
+			data.Filtered.Collapsed++
+		}
+		kept = append(kept, file)
+	}
+	data.Files = kept
+}
+
+// collapseFile keeps a file listed with its line counts but drops its content.
+func collapseFile(file *DiffFile) {
+	file.Collapsed = true
+	file.Hunks = []*DiffHunk{}
+	file.RawDiff = ""
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// prReviewRecord remembers which version of a PR was last opened for review,
+// so a later force-push can be reviewed as a range-diff.
+type prReviewRecord struct {
+	HeadOid    string    `json:"headOid"`
+	BaseOid    string    `json:"baseOid"`
+	ReviewedAt time.Time `json:"reviewedAt"`
+
+	// The version opened before a force-push, kept until the range-diff
+	// between it and the current head has been loaded
+	PreviousHeadOid string `json:"previousHeadOid,omitempty"`
+	PreviousBaseOid string `json:"previousBaseOid,omitempty"`
+}
+
+var prReviewsMu sync.Mutex
+
+// recordPRReview notes that the PR under key was opened at head and base and
+// returns the record. When the head moved, the version opened before becomes
+// the previous one, unless an earlier previous version has not been compared yet.
+func recordPRReview(key string, head string, base string) prReviewRecord {
+	prReviewsMu.Lock()
+	defer prReviewsMu.Unlock()
+
+	reviews := loadPRReviews()
+	rec, ok := reviews[key]
+	if ok && rec.HeadOid != head && rec.PreviousHeadOid == "" {
+		rec.PreviousHeadOid, rec.PreviousBaseOid = rec.HeadOid, rec.BaseOid
+	}
+	if rec.PreviousHeadOid == head {
+		// Pushed back to the version that was reviewed
+		rec.PreviousHeadOid, rec.PreviousBaseOid = "", ""
+	}
+	rec.HeadOid, rec.BaseOid, rec.ReviewedAt = head, base, time.Now()
+
+	reviews[key] = rec
+	savePRReviews(reviews)
+	return rec
+}
+
+// markPRRangeDiffReviewed forgets the previous version of every PR whose
+// previous and current heads are oldHead and newHead.
+func markPRRangeDiffReviewed(oldHead string, newHead string) {
+	prReviewsMu.Lock()
+	defer prReviewsMu.Unlock()
+
+	reviews := loadPRReviews()
+	changed := false
+	for key, rec := range reviews {
+		if rec.PreviousHeadOid == oldHead && rec.HeadOid == newHead {
+			rec.PreviousHeadOid, rec.PreviousBaseOid = "", ""
+			reviews[key] = rec
+			changed = true
+		}
+	}
+	if changed {
+		savePRReviews(reviews)
+	}
+}
+
+func loadPRReviews() map[string]prReviewRecord {
+	reviews := map[string]prReviewRecord{}
+	if storePath := defaultPRReviewStorePath(); storePath != "" {
+		if bytes, err := os.ReadFile(storePath); err == nil {
+			_ = json.Unmarshal(bytes, &reviews)
+		}
+	}
+	return reviews
+}
+
+func savePRReviews(reviews map[string]prReviewRecord) {
+	storePath := defaultPRReviewStorePath()
+	if storePath == "" {
+		return
+	}
+	bytes, err := json.MarshalIndent(reviews, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(storePath), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(storePath, bytes, 0o644)
+}
+
+func defaultPRReviewStorePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, "diffdragon", "pr-reviews.json")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordPRReviewKeepsPreviousUntilRangeDiff(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	const key = "o/r#1"
+
+	if rec := recordPRReview(key, "h1", "b1"); rec.PreviousHeadOid != "" {
+		t.Fatalf("first open has previous %q", rec.PreviousHeadOid)
+	}
+	if rec := recordPRReview(key, "h2", "b1"); rec.PreviousHeadOid != "h1" || rec.PreviousBaseOid != "b1" {
+		t.Fatalf("after push: previous = %q/%q, want h1/b1", rec.PreviousHeadOid, rec.PreviousBaseOid)
+	}
+	// Opened again, and pushed again, before the range-diff was loaded
+	if rec := recordPRReview(key, "h2", "b1"); rec.PreviousHeadOid != "h1" {
+		t.Fatalf("reopen: previous = %q, want h1", rec.PreviousHeadOid)
+	}
+	if rec := recordPRReview(key, "h3", "b2"); rec.PreviousHeadOid != "h1" || rec.HeadOid != "h3" {
+		t.Fatalf("second push: previous = %q head = %q, want h1 and h3", rec.PreviousHeadOid, rec.HeadOid)
+	}
+
+	markPRRangeDiffReviewed("h1", "h2")
+	if rec := recordPRReview(key, "h3", "b2"); rec.PreviousHeadOid != "h1" {
+		t.Fatalf("range-diff of a stale head cleared previous")
+	}
+	markPRRangeDiffReviewed("h1", "h3")
+	if rec := recordPRReview(key, "h3", "b2"); rec.PreviousHeadOid != "" {
+		t.Fatalf("previous = %q after its range-diff was loaded", rec.PreviousHeadOid)
+	}
+	if rec := recordPRReview(key, "h4", "b2"); rec.PreviousHeadOid != "h3" {
+		t.Fatalf("next push: previous = %q, want h3", rec.PreviousHeadOid)
+	}
+}
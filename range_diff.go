@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RangeDiffSpec compares two versions of a patch series, e.g. a PR before and
+// after a force-push. Without a commit pair the whole series is compared.
+type RangeDiffSpec struct {
+	OldBase string `json:"oldBase"`
+	OldHead string `json:"oldHead"`
+	NewBase string `json:"newBase"`
+	NewHead string `json:"newHead"`
+
+	OldCommit string `json:"oldCommit,omitempty"` // Review a single commit pair from ListRangeDiff
+	NewCommit string `json:"newCommit,omitempty"` // Empty OldCommit means the commit was added
+}
+
+// RangeDiffPair is one line of `git range-diff`: a commit matched across both versions.
+type RangeDiffPair struct {
+	Status   string `json:"status"` // unchanged, changed, added, removed
+	OldIndex int    `json:"oldIndex,omitempty"`
+	NewIndex int    `json:"newIndex,omitempty"`
+	OldSHA   string `json:"oldSha,omitempty"`
+	NewSHA   string `json:"newSha,omitempty"`
+	Subject  string `json:"subject"`
+}
+
+var rangeDiffLineRe = regexp.MustCompile(`^\s*(-|\d+):\s+(-+|[0-9a-f]+)\s+([=!<>])\s+(-|\d+):\s+(-+|[0-9a-f]+)\s+(.*)$`)
+
+// ListRangeDiff runs `git range-diff` for the spec and returns its commit pairs.
+func ListRangeDiff(repoPath string, spec RangeDiffSpec) ([]RangeDiffPair, error) {
+	oldRange := spec.OldBase + ".." + spec.OldHead
+	newRange := spec.NewBase + ".." + spec.NewHead
+
+	out, err := runGit(repoPath, "range-diff", "--no-color", "-s", oldRange, newRange)
+	if err != nil {
+		return nil, fmt.Errorf("range-diff failed: %w", err)
+	}
+
+	// range-diff abbreviates object names, so expand them against each range
+	oldCommits, err := runGit(repoPath, "rev-list", "--no-merges", oldRange)
+	if err != nil {
+		return nil, err
+	}
+	newCommits, err := runGit(repoPath, "rev-list", "--no-merges", newRange)
+	if err != nil {
+		return nil, err
+	}
+	oldList, newList := splitGitLines(oldCommits), splitGitLines(newCommits)
+	expand := func(short string, full []string) string {
+		for _, sha := range full {
+			if strings.HasPrefix(sha, short) {
+				return sha
+			}
+		}
+		return short
+	}
+
+	pairs := []RangeDiffPair{}
+	for _, line := range strings.Split(out, "\n") {
+		m := rangeDiffLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		pair := RangeDiffPair{Subject: m[6]}
+		if m[1] != "-" {
+			pair.OldIndex, _ = strconv.Atoi(m[1])
+			pair.OldSHA = expand(m[2], oldList)
+		}
+		if m[4] != "-" {
+			pair.NewIndex, _ = strconv.Atoi(m[4])
+			pair.NewSHA = expand(m[5], newList)
+		}
+		switch m[3] {
+		case "=":
+			pair.Status = "unchanged"
+		case "!":
+			pair.Status = "changed"
+		case "<":
+			pair.Status = "removed"
+		case ">":
+			pair.Status = "added"
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// rangeDiffComparison resolves an interdiff: the old change is replayed onto
+// the new parent in a scratch index, and the result is diffed against the new
+// version, so upstream changes picked up by the rebase don't show up.
+func rangeDiffComparison(repoPath string, spec RangeDiffSpec) (diffComparison, error) {
+	var oldFrom, oldTo, newFrom, newTo string
+
+	if spec.OldCommit == "" && spec.NewCommit == "" {
+		newHead, err := resolveCommit(repoPath, spec.NewHead)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		oldHead, err := resolveCommit(repoPath, spec.OldHead)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		oldFrom, err = mergeBaseOf(repoPath, spec.OldBase, oldHead)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		newFrom, err = mergeBaseOf(repoPath, spec.NewBase, newHead)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		oldTo, newTo = oldHead, newHead
+	} else {
+		if spec.NewCommit == "" {
+			return diffComparison{}, fmt.Errorf("commit was dropped in the new version; there is nothing to compare it with")
+		}
+		newCommit, err := resolveCommit(repoPath, spec.NewCommit)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		newFrom, err = commitRangeBase(repoPath, newCommit)
+		if err != nil {
+			return diffComparison{}, err
+		}
+		newTo = newCommit
+
+		if spec.OldCommit != "" {
+			oldTo, err = resolveCommit(repoPath, spec.OldCommit)
+			if err != nil {
+				return diffComparison{}, err
+			}
+			oldFrom, err = commitRangeBase(repoPath, oldTo)
+			if err != nil {
+				return diffComparison{}, err
+			}
+		}
+	}
+
+	cmp := diffComparison{
+		BaseLabel: "previous version",
+		HeadLabel: "new version",
+		HeadSHA:   newTo,
+		New:       fileSource{Kind: "ref", Ref: newTo},
+	}
+
+	base := newFrom
+	if oldTo != "" {
+		replayed, err := replayChangeTree(repoPath, oldFrom, oldTo, newFrom)
+		if err != nil {
+			// The old change no longer applies on the new parent; compare the
+			// two versions directly, upstream changes included.
+			base = oldTo
+			cmp.Notice = "The previous version does not apply cleanly on the new base, so this compares the two versions directly and includes upstream changes."
+		} else {
+			base = replayed
+		}
+	}
+
+	cmp.Args = []string{base, newTo}
+	cmp.Old = fileSource{Kind: "ref", Ref: base}
+	cmp.BaseSHA = oldTo
+	return cmp, nil
+}
+
+func mergeBaseOf(repoPath string, a string, b string) (string, error) {
+	out, err := runGit(repoPath, "merge-base", a, b)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute merge base of %s and %s: %w", a, b, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+var (
+	scratchTreesMu sync.Mutex
+	scratchTrees   = map[string]string{}
+)
+
+// buildScratchTree runs build with a throwaway index file and returns the tree
+// it writes. Trees are cached by key since file content requests resolve the
+// comparison again; the inputs are immutable commits, so the result is too.
+func buildScratchTree(repoPath string, key string, build func(env []string) error) (string, error) {
+	key = repoPath + "\x00" + key // Trees are only written to the repository that built them
+	scratchTreesMu.Lock()
+	tree, ok := scratchTrees[key]
+	scratchTreesMu.Unlock()
+	if ok {
+		return tree, nil
+	}
+
+	err := withScratchIndex(func(env []string) error {
+		if err := build(env); err != nil {
+			return err
+		}
+		out, err := gitStdout(repoPath, env, "", "write-tree")
+		if err != nil {
+			return err
+		}
+		tree = strings.TrimSpace(out)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	scratchTreesMu.Lock()
+	scratchTrees[key] = tree
+	scratchTreesMu.Unlock()
+	return tree, nil
+}
+
+// withScratchIndex runs fn with GIT_INDEX_FILE pointing at a throwaway index.
+func withScratchIndex(fn func(env []string) error) error {
+	indexFile, err := os.CreateTemp("", "diffdragon-index-*")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch index: %w", err)
+	}
+	indexPath := indexFile.Name()
+	indexFile.Close()
+	os.Remove(indexPath) // git wants to create the index itself
+	defer os.Remove(indexPath)
+
+	return fn([]string{"GIT_INDEX_FILE=" + indexPath})
+}
+
+// replayChangeTree applies the change from..to onto the tree of onto and
+// returns the resulting tree.
+func replayChangeTree(repoPath string, from string, to string, onto string) (string, error) {
+	return buildScratchTree(repoPath, "replay "+from+" "+to+" "+onto, func(env []string) error {
+		patch, err := gitStdout(repoPath, nil, "", "diff", "--binary", "--full-index", "--no-color", "--no-ext-diff", "--no-renames", "--src-prefix=a/", "--dst-prefix=b/", from, to)
+		if err != nil {
+			return err
+		}
+		if _, err := gitStdout(repoPath, env, "", "read-tree", onto); err != nil {
+			return err
+		}
+		if strings.TrimSpace(patch) == "" {
+			return nil
+		}
+		_, err = gitStdout(repoPath, env, patch, "apply", "--cached", "--binary")
+		return err
+	})
+}
+
+// gitStdout runs git with extra environment and stdin, returning stdout only.
+func gitStdout(repoPath string, env []string, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), env...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// riskRulesFile is the rules file name, read from the user config dir and
+// from .diffdragon/ in the repository, in that order.
+const riskRulesFile = "risk.yaml"
+
+// RiskRule is one heuristic risk rule. A rule fires when the file's language
+// is in scope and any of its paths, words or line patterns match.
+type RiskRule struct {
+	ID        string   `json:"id"`
+	Reason    string   `json:"reason"`
+	Score     int      `json:"score"`               // Added to the file's risk; negative to lower noise
+	Paths     []string `json:"paths,omitempty"`     // Gitignore-style globs, matched case-insensitively
+	Words     []string `json:"words,omitempty"`     // Globs matched against each word of the path, e.g. "ci" or "auth*"
+	Added     []string `json:"added,omitempty"`     // Regexes matched against added lines
+	Removed   []string `json:"removed,omitempty"`   // Regexes matched against removed lines
+	Changed   []string `json:"changed,omitempty"`   // Regexes matched against added and removed lines
+	Languages []string `json:"languages,omitempty"` // Only files in these languages; empty for all
+	Source    string   `json:"source"`              // "builtin" or the file that last defined the rule
+}
+
+// RiskEvidence shows what made a risk rule fire for a file.
+type RiskEvidence struct {
+	Rule    string `json:"rule"`
+	Reason  string `json:"reason"`
+	Match   string `json:"match"`             // path, added or removed
+	Lines   []int  `json:"lines,omitempty"`   // New line numbers of added lines, old ones of removed lines; capped
+	Count   int    `json:"count,omitempty"`   // Matching lines, including those past the cap
+	Snippet string `json:"snippet,omitempty"` // The first matching line, or the matching path or word
+}
+
+// RiskRuleSet is the effective rule set for a repository.
+type RiskRuleSet struct {
+	Rules   []RiskRule `json:"rules"`
+	Sources []string   `json:"sources"` // Rules files that were loaded
+	Errors  []string   `json:"errors"`  // Invalid files and rules; those rules are skipped
+
+	SecretAllow []SecretAllow `json:"secretAllow"` // Exemptions from the secret scanner
+
+	compiled    []compiledRiskRule
+	secretAllow []compiledSecretAllow
+}
+
+type compiledRiskRule struct {
+	rule      RiskRule
+	paths     []*regexp.Regexp
+	words     []*regexp.Regexp
+	added     []*regexp.Regexp
+	removed   []*regexp.Regexp
+	languages map[string]bool
+}
+
+// riskRulesYAML is the layout of risk.yaml:
+//
+//	builtin: true            # false starts from an empty rule set
+//	rules:
+//	  - id: billing-core
+//	    reason: Touches billing core
+//	    score: 50
+//	    paths: [billing-core/]
+//	  - id: config           # override a built-in rule by id
+//	    score: 5
+//	  - id: termination
+//	    disabled: true
+//	secrets:
+//	  allow:                 # findings the secret scanner should ignore
+//	    - paths: [testdata/**]
+//	    - match: 'sk_test_'
+//	      kinds: [stripe-key]
+type riskRulesYAML struct {
+	Builtin     *bool
+	Rules       []riskRuleYAML
+	SecretAllow []SecretAllow
+}
+
+// riskRuleYAML is a rule as written in risk.yaml. Unset fields of a rule
+// that reuses an id keep the earlier definition's values.
+type riskRuleYAML struct {
+	ID        string
+	Reason    *string
+	Score     *int
+	Paths     []string
+	Words     []string
+	Added     []string
+	Removed   []string
+	Changed   []string
+	Languages []string
+	Disabled  bool
+}
+
+// builtinRiskRules is the default rule set, applied before any rules file.
+var builtinRiskRules = []RiskRule{
+	{
+		ID:     "auth",
+		Words:  []string{"auth*", "*oauth*", "login*", "logout", "session*", "token", "tokens", "jwt*", "credential*", "password*", "passwd", "secret*"},
+		Score:  30,
+		Reason: "Touches authentication/authorization code",
+	},
+	{
+		ID:     "crypto",
+		Words:  []string{"crypto*", "*crypt", "encrypt*", "decrypt*", "hash", "hashing", "hasher", "cert", "certs", "certificate*", "tls", "ssl", "x509"},
+		Score:  30,
+		Reason: "Touches cryptography/security code",
+	},
+	{
+		ID:      "schema",
+		Words:   []string{"migration*", "migrate", "schema*", "database*", "db"},
+		Changed: []string{`(?i)\b(create|alter|drop) table\b`, `(?i)\b(create|drop) index\b`},
+		Score:   25,
+		Reason:  "Database schema or migration change",
+	},
+	{
+		ID:      "sql",
+		Changed: []string{`(?i)\b(select|insert|update|delete) `, `(?i)\b(exec|raw|execute)\(`, `(?i)rawquery`},
+		Score:   20,
+		Reason:  "Contains raw SQL or query execution",
+	},
+	{
+		ID:     "api",
+		Words:  []string{"api", "route*", "router*", "handler*", "controller*", "endpoint*", "middleware*"},
+		Score:  20,
+		Reason: "Modifies public API surface or middleware",
+	},
+	{
+		ID:     "permissions",
+		Words:  []string{"permission*", "rbac", "role", "roles", "access", "policy", "policies", "acl", "acls"},
+		Score:  25,
+		Reason: "Touches permission/access control logic",
+	},
+	{
+		ID:      "termination",
+		Changed: []string{`panic\(`, `os\.Exit`, `log\.Fatal`, `process\.exit`},
+		Score:   15,
+		Reason:  "Contains abrupt termination calls",
+	},
+	{
+		ID:     "config",
+		Paths:  []string{".env", ".env.*", "*.env"},
+		Words:  []string{"config*", "conf", "setting*"},
+		Score:  15,
+		Reason: "Configuration file change",
+	},
+	{
+		ID:     "infra",
+		Words:  []string{"docker*", "k8s", "kubernetes", "helm", "deploy*", "ci", "cd", "pipeline*", "terraform", "tf", "tfvars"},
+		Score:  15,
+		Reason: "Infrastructure/deployment configuration change",
+	},
+	{
+		ID:     "payments",
+		Words:  []string{"payment*", "billing", "invoice*", "stripe", "subscription*", "charge*"},
+		Score:  25,
+		Reason: "Touches payment/billing code",
+	},
+	{
+		ID:      "removed-error-handling",
+		Removed: []string{`^\s*(if err\b|catch\b|except\b)`},
+		Score:   15,
+		Reason:  "Removes error handling",
+	},
+}
+
+// setRiskRules attaches the rules the diff is analyzed with and reports their errors.
+func (data *DiffData) setRiskRules(rules *RiskRuleSet) {
+	data.riskRules = rules
+	data.RiskRuleErrors = rules.Errors
+}
+
+// effectiveRiskRules returns the rules loaded with the diff, or the built-in
+// and user rules when none were.
+func (data *DiffData) effectiveRiskRules() *RiskRuleSet {
+	if data.riskRules == nil {
+		data.setRiskRules(LoadRiskRules(""))
+	}
+	return data.riskRules
+}
+
+// riskRuleFiles lists the rules files for a repository, lowest precedence first.
+func riskRuleFiles(repoPath string) []string {
+	var files []string
+	if configDir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(configDir, "diffdragon", riskRulesFile))
+	}
+	if repoPath != "" {
+		files = append(files, filepath.Join(repoPath, ".diffdragon", riskRulesFile))
+	}
+	return files
+}
+
+// LoadRiskRules builds the effective rule set: the built-in rules, then the
+// user's rules file, then the repository's. Invalid files and rules are
+// reported in Errors and left out rather than failing the review.
+func LoadRiskRules(repoPath string) *RiskRuleSet {
+	set := &RiskRuleSet{Rules: []RiskRule{}, Sources: []string{}, Errors: []string{}, SecretAllow: []SecretAllow{}}
+
+	rules := make([]RiskRule, 0, len(builtinRiskRules))
+	for _, rule := range builtinRiskRules {
+		rule.Source = "builtin"
+		rules = append(rules, rule)
+	}
+
+	for _, path := range riskRuleFiles(repoPath) {
+		file, err := readRiskRulesFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			set.Errors = append(set.Errors, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		set.Sources = append(set.Sources, path)
+
+		if file.Builtin != nil && !*file.Builtin {
+			kept := rules[:0]
+			for _, rule := range rules {
+				if rule.Source != "builtin" {
+					kept = append(kept, rule)
+				}
+			}
+			rules = kept
+		}
+		for i, def := range file.Rules {
+			var err error
+			rules, err = mergeRiskRule(rules, def, path)
+			if err != nil {
+				set.Errors = append(set.Errors, fmt.Sprintf("%s: rule %d (%s): %v", path, i+1, def.ID, err))
+			}
+		}
+		for i, allow := range file.SecretAllow {
+			allow.Source = path
+			compiled, err := compileSecretAllow(allow)
+			if err != nil {
+				set.Errors = append(set.Errors, fmt.Sprintf("%s: secrets allow entry %d: %v", path, i+1, err))
+				continue
+			}
+			set.SecretAllow = append(set.SecretAllow, allow)
+			set.secretAllow = append(set.secretAllow, compiled)
+		}
+	}
+
+	for _, rule := range rules {
+		compiled, err := compileRiskRule(rule)
+		if err != nil {
+			set.Errors = append(set.Errors, fmt.Sprintf("%s: rule %q: %v", rule.Source, rule.ID, err))
+			continue
+		}
+		set.Rules = append(set.Rules, rule)
+		set.compiled = append(set.compiled, compiled)
+	}
+	return set
+}
+
+func readRiskRulesFile(path string) (riskRulesYAML, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return riskRulesYAML{}, err
+	}
+	return parseRiskRulesYAML(string(data))
+}
+
+// mergeRiskRule adds def to rules, or overrides or removes the rule with the same id.
+func mergeRiskRule(rules []RiskRule, def riskRuleYAML, source string) ([]RiskRule, error) {
+	id := strings.TrimSpace(def.ID)
+	if id == "" {
+		return rules, fmt.Errorf("id is required")
+	}
+
+	index := -1
+	for i := range rules {
+		if rules[i].ID == id {
+			index = i
+			break
+		}
+	}
+	if def.Disabled {
+		if index >= 0 {
+			rules = append(rules[:index], rules[index+1:]...)
+		}
+		return rules, nil
+	}
+
+	rule := RiskRule{ID: id}
+	if index >= 0 {
+		rule = rules[index]
+	}
+	rule.Source = source
+	if def.Reason != nil {
+		rule.Reason = strings.TrimSpace(*def.Reason)
+	}
+	if def.Score != nil {
+		rule.Score = *def.Score
+	}
+	if def.Paths != nil {
+		rule.Paths = def.Paths
+	}
+	if def.Words != nil {
+		rule.Words = def.Words
+	}
+	if def.Added != nil {
+		rule.Added = def.Added
+	}
+	if def.Removed != nil {
+		rule.Removed = def.Removed
+	}
+	if def.Changed != nil {
+		rule.Changed = def.Changed
+	}
+	if def.Languages != nil {
+		rule.Languages = def.Languages
+	}
+
+	if rule.Reason == "" {
+		return rules, fmt.Errorf("reason is required")
+	}
+	if rule.Score < -100 || rule.Score > 100 {
+		return rules, fmt.Errorf("score must be between -100 and 100")
+	}
+	if len(rule.Paths)+len(rule.Words)+len(rule.Added)+len(rule.Removed)+len(rule.Changed) == 0 {
+		return rules, fmt.Errorf("needs at least one of paths, words, added, removed or changed")
+	}
+
+	if index >= 0 {
+		rules[index] = rule
+	} else {
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func compileRiskRule(rule RiskRule) (compiledRiskRule, error) {
+	compiled := compiledRiskRule{rule: rule}
+	for _, glob := range rule.Paths {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			return compiled, fmt.Errorf("empty path glob")
+		}
+		compiled.paths = append(compiled.paths, regexp.MustCompile("(?i)"+compilePathGlob(glob).String()))
+	}
+	for _, word := range rule.Words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || strings.Contains(word, "/") {
+			return compiled, fmt.Errorf("invalid word %q", word)
+		}
+		compiled.words = append(compiled.words, compilePathGlob(word))
+	}
+
+	compileAll := func(field string, patterns []string) ([]*regexp.Regexp, error) {
+		var res []*regexp.Regexp
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern: %w", field, err)
+			}
+			res = append(res, re)
+		}
+		return res, nil
+	}
+	added, err := compileAll("added", rule.Added)
+	if err != nil {
+		return compiled, err
+	}
+	removed, err := compileAll("removed", rule.Removed)
+	if err != nil {
+		return compiled, err
+	}
+	changed, err := compileAll("changed", rule.Changed)
+	if err != nil {
+		return compiled, err
+	}
+	compiled.added = append(added, changed...)
+	compiled.removed = append(removed, changed...)
+
+	if len(rule.Languages) > 0 {
+		compiled.languages = map[string]bool{}
+		for _, lang := range rule.Languages {
+			compiled.languages[normalizeLanguageName(lang)] = true
+		}
+	}
+	return compiled, nil
+}
+
+// riskMatch is a rule that fired and what it matched.
+type riskMatch struct {
+	rule     RiskRule
+	evidence []RiskEvidence
+}
+
+// maxEvidenceLines caps the line numbers kept per evidence entry.
+const maxEvidenceLines = 20
+
+// matchPathRules returns the rules whose paths or words match file.
+func (set *RiskRuleSet) matchPathRules(file *DiffFile) []riskMatch {
+	words := pathWords(file.Path)
+
+	var matches []riskMatch
+	for _, rule := range set.compiled {
+		if !rule.appliesTo(file) {
+			continue
+		}
+		snippet, ok := matchPathGlobs(rule.paths, file.Path)
+		if !ok {
+			snippet, ok = matchPathWords(rule.words, words)
+		}
+		if ok {
+			ev := RiskEvidence{Rule: rule.rule.ID, Reason: rule.rule.Reason, Match: "path", Snippet: snippet}
+			matches = append(matches, riskMatch{rule: rule.rule, evidence: []RiskEvidence{ev}})
+		}
+	}
+	return matches
+}
+
+// matchLineRules returns the rules whose line patterns match an added or
+// removed line of hunk. Context lines are never matched.
+func (set *RiskRuleSet) matchLineRules(file *DiffFile, hunk *DiffHunk) []riskMatch {
+	var added, removed []DiffLine
+	for _, line := range hunk.Lines {
+		switch line.Kind {
+		case "add":
+			added = append(added, line)
+		case "del":
+			removed = append(removed, line)
+		}
+	}
+
+	var matches []riskMatch
+	for _, rule := range set.compiled {
+		if !rule.appliesTo(file) {
+			continue
+		}
+		var evidence []RiskEvidence
+		if ev, ok := matchLines(rule.added, added, "added"); ok {
+			evidence = append(evidence, ev)
+		}
+		if ev, ok := matchLines(rule.removed, removed, "removed"); ok {
+			evidence = append(evidence, ev)
+		}
+		if len(evidence) == 0 {
+			continue
+		}
+		for i := range evidence {
+			evidence[i].Rule, evidence[i].Reason = rule.rule.ID, rule.rule.Reason
+		}
+		matches = append(matches, riskMatch{rule: rule.rule, evidence: evidence})
+	}
+	return matches
+}
+
+func (rule compiledRiskRule) appliesTo(file *DiffFile) bool {
+	return rule.languages == nil || rule.languages[file.Language]
+}
+
+// mergeEvidence folds ev into list, combining it with an entry for the same
+// rule and match kind from another hunk.
+func mergeEvidence(list []RiskEvidence, ev RiskEvidence) []RiskEvidence {
+	for i := range list {
+		if list[i].Rule != ev.Rule || list[i].Match != ev.Match {
+			continue
+		}
+		for _, n := range ev.Lines {
+			if len(list[i].Lines) < maxEvidenceLines {
+				list[i].Lines = append(list[i].Lines, n)
+			}
+		}
+		list[i].Count += ev.Count
+		return list
+	}
+	return append(list, ev)
+}
+
+func matchPathGlobs(patterns []*regexp.Regexp, path string) (string, bool) {
+	for _, re := range patterns {
+		if re.MatchString(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func matchPathWords(patterns []*regexp.Regexp, words []string) (string, bool) {
+	for _, re := range patterns {
+		for _, word := range words {
+			if re.MatchString(word) {
+				return word, true
+			}
+		}
+	}
+	return "", false
+}
+
+// matchLines collects the lines any pattern matches. Added lines are
+// numbered in the new file, removed lines in the old one.
+func matchLines(patterns []*regexp.Regexp, lines []DiffLine, kind string) (RiskEvidence, bool) {
+	ev := RiskEvidence{Match: kind}
+	if len(patterns) == 0 {
+		return ev, false
+	}
+	for _, line := range lines {
+		hit := false
+		for _, re := range patterns {
+			if re.MatchString(line.Content) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			continue
+		}
+		if ev.Snippet == "" {
+			ev.Snippet = truncate(strings.TrimSpace(line.Content), 200)
+		}
+		num := line.NewLine
+		if kind == "removed" {
+			num = line.OldLine
+		}
+		if len(ev.Lines) < maxEvidenceLines {
+			ev.Lines = append(ev.Lines, num)
+		}
+		ev.Count++
+	}
+	return ev, ev.Count > 0
+}
+
+// pathWords splits a path into lowercase words at separators, punctuation
+// and camelCase boundaries: "ci/GitLabDeploy-v2.yml" gives ci, git, lab,
+// deploy, v2, yml.
+func pathWords(path string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	runes := []rune(path)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			// A new word starts at an upper-case letter after a lower-case
+			// one, or before a lower-case one in a run of capitals (HTTPServer)
+			if unicode.IsUpper(r) && i > 0 && len(word) > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					flush()
+				}
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
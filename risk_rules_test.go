@@ -0,0 +1,203 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTestRiskRules writes content as the repository's risk.yaml, with an
+// empty user config dir, and loads the effective rule set.
+func loadTestRiskRules(t *testing.T, content string) *RiskRuleSet {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, ".diffdragon"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".diffdragon", riskRulesFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadRiskRules(repo)
+}
+
+func findRiskRule(set *RiskRuleSet, id string) (RiskRule, bool) {
+	for _, rule := range set.Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return RiskRule{}, false
+}
+
+func TestMergeRiskRule(t *testing.T) {
+	base := []RiskRule{
+		{ID: "config", Reason: "Config", Score: 10, Paths: []string{"*.yaml"}, Source: "builtin"},
+		{ID: "sql", Reason: "SQL", Score: 20, Changed: []string{`select `}, Source: "builtin"},
+	}
+
+	tests := []struct {
+		name    string
+		def     riskRuleYAML
+		want    []RiskRule
+		wantErr string
+	}{
+		{
+			name: "add",
+			def:  riskRuleYAML{ID: "billing", Reason: strPtr("Billing"), Score: intPtr(50), Paths: []string{"billing/"}},
+			want: append(append([]RiskRule{}, base...), RiskRule{ID: "billing", Reason: "Billing", Score: 50, Paths: []string{"billing/"}, Source: "test"}),
+		},
+		{
+			name: "override keeps unset fields",
+			def:  riskRuleYAML{ID: "config", Score: intPtr(5)},
+			want: []RiskRule{
+				{ID: "config", Reason: "Config", Score: 5, Paths: []string{"*.yaml"}, Source: "test"},
+				base[1],
+			},
+		},
+		{
+			name: "override replaces lists",
+			def:  riskRuleYAML{ID: "sql", Changed: []string{`exec\(`}, Languages: []string{"go"}},
+			want: []RiskRule{
+				base[0],
+				{ID: "sql", Reason: "SQL", Score: 20, Changed: []string{`exec\(`}, Languages: []string{"go"}, Source: "test"},
+			},
+		},
+		{
+			name: "disable",
+			def:  riskRuleYAML{ID: "config", Disabled: true},
+			want: []RiskRule{base[1]},
+		},
+		{
+			name: "disable unknown id",
+			def:  riskRuleYAML{ID: "nope", Disabled: true},
+			want: base,
+		},
+		{name: "missing id", def: riskRuleYAML{Reason: strPtr("R")}, wantErr: "id is required"},
+		{name: "missing reason", def: riskRuleYAML{ID: "new", Paths: []string{"x"}}, wantErr: "reason is required"},
+		{name: "score out of range", def: riskRuleYAML{ID: "config", Score: intPtr(101)}, wantErr: "score must be between"},
+		{name: "no matchers", def: riskRuleYAML{ID: "new", Reason: strPtr("R")}, wantErr: "needs at least one of"},
+		{name: "override clears matchers", def: riskRuleYAML{ID: "config", Paths: []string{}}, wantErr: "needs at least one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := append([]RiskRule{}, base...)
+			got, err := mergeRiskRule(rules, tt.def, "test")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeRiskRule: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRiskRules(t *testing.T) {
+	t.Run("readme example", func(t *testing.T) {
+		set := loadTestRiskRules(t, `rules:
+  - id: billing-core
+    reason: Touches billing core
+    score: 50
+    paths: [billing-core/]
+  - id: go-todo
+    reason: Adds a TODO
+    score: 5
+    languages: [go]
+    added: ['TODO|FIXME']
+  - id: config          # Reuse a built-in id to change only some fields
+    score: 5
+  - id: termination     # Or to turn it off
+    disabled: true
+`)
+		if len(set.Errors) > 0 {
+			t.Fatalf("errors: %v", set.Errors)
+		}
+		if len(set.Sources) != 1 {
+			t.Errorf("sources = %v, want the repository file", set.Sources)
+		}
+		if _, ok := findRiskRule(set, "termination"); ok {
+			t.Error("disabled built-in rule termination is still loaded")
+		}
+		config, ok := findRiskRule(set, "config")
+		if !ok || config.Score != 5 || config.Reason == "" || len(config.Paths)+len(config.Words) == 0 {
+			t.Errorf("config override = %+v, want score 5 with the built-in reason and matchers", config)
+		}
+		if config.Source == "builtin" {
+			t.Error("overridden rule still has source builtin")
+		}
+		if _, ok := findRiskRule(set, "auth"); !ok {
+			t.Error("untouched built-in rule auth is missing")
+		}
+		todo, ok := findRiskRule(set, "go-todo")
+		if !ok || !reflect.DeepEqual(todo.Added, []string{"TODO|FIXME"}) || !reflect.DeepEqual(todo.Languages, []string{"go"}) {
+			t.Errorf("go-todo = %+v", todo)
+		}
+		if len(set.Rules) != len(builtinRiskRules)+1 {
+			t.Errorf("got %d rules, want %d", len(set.Rules), len(builtinRiskRules)+1)
+		}
+	})
+
+	t.Run("builtin false", func(t *testing.T) {
+		set := loadTestRiskRules(t, `builtin: false
+rules:
+  - id: only
+    reason: Only rule
+    paths: [x/]
+`)
+		if len(set.Errors) > 0 {
+			t.Fatalf("errors: %v", set.Errors)
+		}
+		if len(set.Rules) != 1 || set.Rules[0].ID != "only" {
+			t.Errorf("rules = %+v, want only the file's rule", set.Rules)
+		}
+	})
+
+	t.Run("invalid rules are skipped and reported", func(t *testing.T) {
+		set := loadTestRiskRules(t, `builtin: false
+rules:
+  - id: good
+    reason: Good
+    paths: [x/]
+  - id: no-reason
+    paths: [y/]
+  - id: bad-regex
+    reason: Bad
+    added: ['(']
+`)
+		if len(set.Rules) != 1 || set.Rules[0].ID != "good" {
+			t.Errorf("rules = %+v, want only good", set.Rules)
+		}
+		if len(set.Errors) != 2 {
+			t.Fatalf("errors = %v, want 2", set.Errors)
+		}
+		if !strings.Contains(set.Errors[0], "rule 2 (no-reason): reason is required") {
+			t.Errorf("errors[0] = %q", set.Errors[0])
+		}
+		if !strings.Contains(set.Errors[1], `rule "bad-regex"`) {
+			t.Errorf("errors[1] = %q", set.Errors[1])
+		}
+	})
+
+	t.Run("invalid file keeps the built-in rules", func(t *testing.T) {
+		set := loadTestRiskRules(t, "rules:\n\t- id: a\n")
+		if len(set.Errors) != 1 || !strings.Contains(set.Errors[0], "tabs are not allowed") {
+			t.Errorf("errors = %v", set.Errors)
+		}
+		if len(set.Sources) != 0 || len(set.Rules) != len(builtinRiskRules) {
+			t.Errorf("got %d rules from %v, want the built-in ones", len(set.Rules), set.Sources)
+		}
+	})
+}
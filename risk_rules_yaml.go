@@ -0,0 +1,430 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// risk.yaml is read with a small parser for the subset of YAML it needs:
+// nested mappings and lists by indentation, "- " list items, flow lists like
+// [a, "b"], plain, 'single' and "double" quoted scalars, and # comments.
+// Anchors, multi-line scalars and flow mappings are not supported.
+
+// yamlLine is a non-blank line with its indentation and comment removed.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlNode is a parsed value: a scalar, a list or a mapping.
+type yamlNode struct {
+	line   int
+	scalar *string
+	list   []*yamlNode
+	keys   []string // Mapping keys in file order
+	fields map[string]*yamlNode
+}
+
+// parseRiskRulesYAML reads a risk.yaml document into its rule definitions.
+func parseRiskRulesYAML(content string) (riskRulesYAML, error) {
+	var file riskRulesYAML
+
+	lines, err := splitYAMLLines(content)
+	if err != nil || len(lines) == 0 {
+		return file, err
+	}
+	root, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return file, err
+	}
+	if next < len(lines) {
+		return file, fmt.Errorf("line %d: unexpected indentation", lines[next].num)
+	}
+	if root.fields == nil {
+		return file, fmt.Errorf("line %d: expected a mapping with a rules list", root.line)
+	}
+
+	for _, key := range root.keys {
+		node := root.fields[key]
+		switch key {
+		case "builtin":
+			b, err := node.yamlBool()
+			if err != nil {
+				return file, err
+			}
+			file.Builtin = &b
+		case "secrets":
+			allow, err := decodeSecretsYAML(node)
+			if err != nil {
+				return file, err
+			}
+			file.SecretAllow = allow
+		case "rules":
+			if node.scalar != nil && *node.scalar == "" {
+				continue
+			}
+			if node.list == nil {
+				return file, fmt.Errorf("line %d: rules must be a list", node.line)
+			}
+			for _, item := range node.list {
+				rule, err := decodeRiskRuleYAML(item)
+				if err != nil {
+					return file, err
+				}
+				file.Rules = append(file.Rules, rule)
+			}
+		default:
+			return file, fmt.Errorf("line %d: unknown field %q", node.line, key)
+		}
+	}
+	return file, nil
+}
+
+func decodeRiskRuleYAML(node *yamlNode) (riskRuleYAML, error) {
+	var rule riskRuleYAML
+	if node.fields == nil {
+		return rule, fmt.Errorf("line %d: each rule must be a mapping", node.line)
+	}
+
+	for _, key := range node.keys {
+		value := node.fields[key]
+		var err error
+		switch key {
+		case "id":
+			rule.ID, err = value.yamlString()
+		case "reason":
+			var s string
+			s, err = value.yamlString()
+			rule.Reason = &s
+		case "score":
+			var n int
+			n, err = value.yamlInt()
+			rule.Score = &n
+		case "paths":
+			rule.Paths, err = value.yamlStrings()
+		case "words":
+			rule.Words, err = value.yamlStrings()
+		case "added":
+			rule.Added, err = value.yamlStrings()
+		case "removed":
+			rule.Removed, err = value.yamlStrings()
+		case "changed":
+			rule.Changed, err = value.yamlStrings()
+		case "languages":
+			rule.Languages, err = value.yamlStrings()
+		case "disabled":
+			rule.Disabled, err = value.yamlBool()
+		default:
+			err = fmt.Errorf("line %d: unknown field %q", value.line, key)
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+// decodeSecretsYAML reads the secrets section, which holds the allow list.
+func decodeSecretsYAML(node *yamlNode) ([]SecretAllow, error) {
+	if node.fields == nil {
+		return nil, fmt.Errorf("line %d: secrets must be a mapping", node.line)
+	}
+	var entries []SecretAllow
+	for _, key := range node.keys {
+		value := node.fields[key]
+		if key != "allow" {
+			return nil, fmt.Errorf("line %d: unknown field %q", value.line, key)
+		}
+		if value.scalar != nil && *value.scalar == "" {
+			continue
+		}
+		if value.list == nil {
+			return nil, fmt.Errorf("line %d: allow must be a list", value.line)
+		}
+		for _, item := range value.list {
+			if item.fields == nil {
+				return nil, fmt.Errorf("line %d: each allow entry must be a mapping", item.line)
+			}
+			var allow SecretAllow
+			for _, field := range item.keys {
+				v := item.fields[field]
+				var err error
+				switch field {
+				case "paths":
+					allow.Paths, err = v.yamlStrings()
+				case "match":
+					allow.Match, err = v.yamlString()
+				case "kinds":
+					allow.Kinds, err = v.yamlStrings()
+				default:
+					err = fmt.Errorf("line %d: unknown field %q", v.line, field)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			entries = append(entries, allow)
+		}
+	}
+	return entries, nil
+}
+
+// splitYAMLLines drops blank lines and comments and measures indentation.
+func splitYAMLLines(content string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.TrimSpace(trimmed) == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: strings.TrimRight(trimmed, " \t")})
+	}
+	return lines, nil
+}
+
+// stripYAMLComment removes a # comment that starts a line or follows a space,
+// outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseYAMLBlock parses the lines starting at i that share indent, returning
+// the node and the index of the first line after it.
+func parseYAMLBlock(lines []yamlLine, i int, indent int) (*yamlNode, int, error) {
+	first := lines[i]
+	if first.text == "-" || strings.HasPrefix(first.text, "- ") {
+		return parseYAMLList(lines, i, indent)
+	}
+	return parseYAMLMapping(lines, i, indent)
+}
+
+func parseYAMLList(lines []yamlLine, i int, indent int) (*yamlNode, int, error) {
+	node := &yamlNode{line: lines[i].num, list: []*yamlNode{}}
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		if line.text != "-" && !strings.HasPrefix(line.text, "- ") {
+			break
+		}
+		content := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+
+		var item *yamlNode
+		var err error
+		switch {
+		case content == "":
+			// The item is the indented block below
+			if i+1 < len(lines) && lines[i+1].indent > indent {
+				item, i, err = parseYAMLBlock(lines, i+1, lines[i+1].indent)
+			} else {
+				item, i = yamlScalarNode(line.num, ""), i+1
+			}
+		case isYAMLMappingEntry(content):
+			// "- key: value" starts a mapping indented to where the key begins
+			itemIndent := indent + len(line.text) - len(content)
+			lines[i] = yamlLine{num: line.num, indent: itemIndent, text: content}
+			item, i, err = parseYAMLMapping(lines, i, itemIndent)
+		default:
+			item, err = parseYAMLScalar(line.num, content)
+			i++
+		}
+		if err != nil {
+			return nil, i, err
+		}
+		node.list = append(node.list, item)
+	}
+	return node, i, nil
+}
+
+func parseYAMLMapping(lines []yamlLine, i int, indent int) (*yamlNode, int, error) {
+	node := &yamlNode{line: lines[i].num, fields: map[string]*yamlNode{}}
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		if !isYAMLMappingEntry(line.text) {
+			return nil, i, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		key, value, _ := strings.Cut(line.text, ":")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if _, dup := node.fields[key]; dup {
+			return nil, i, fmt.Errorf("line %d: duplicate field %q", line.num, key)
+		}
+		i++
+
+		var child *yamlNode
+		var err error
+		switch {
+		case value != "":
+			child, err = parseYAMLScalar(line.num, value)
+		case i < len(lines) && lines[i].indent > indent:
+			child, i, err = parseYAMLBlock(lines, i, lines[i].indent)
+		case i < len(lines) && lines[i].indent == indent && (lines[i].text == "-" || strings.HasPrefix(lines[i].text, "- ")):
+			// A list may sit at the same indentation as its key
+			child, i, err = parseYAMLList(lines, i, indent)
+		default:
+			child = yamlScalarNode(line.num, "")
+		}
+		if err != nil {
+			return nil, i, err
+		}
+		// Errors about a value point at the key that holds it
+		child.line = line.num
+		node.keys = append(node.keys, key)
+		node.fields[key] = child
+	}
+	return node, i, nil
+}
+
+// isYAMLMappingEntry reports whether text is "key: value" or "key:".
+func isYAMLMappingEntry(text string) bool {
+	if text == "" || text[0] == '"' || text[0] == '\'' || text[0] == '[' {
+		return false
+	}
+	key, value, ok := strings.Cut(text, ":")
+	return ok && key != "" && !strings.ContainsAny(key, " \t") && (value == "" || value[0] == ' ')
+}
+
+// parseYAMLScalar parses a scalar or a flow list such as [a, "b, c"].
+func parseYAMLScalar(num int, text string) (*yamlNode, error) {
+	if !strings.HasPrefix(text, "[") {
+		s, err := unquoteYAML(num, text)
+		if err != nil {
+			return nil, err
+		}
+		return yamlScalarNode(num, s), nil
+	}
+
+	if !strings.HasSuffix(text, "]") {
+		return nil, fmt.Errorf("line %d: unterminated flow list", num)
+	}
+	node := &yamlNode{line: num, list: []*yamlNode{}}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	if inner == "" {
+		return node, nil
+	}
+
+	var quote byte
+	start := 0
+	for i := 0; i <= len(inner); i++ {
+		if i < len(inner) {
+			c := inner[i]
+			if quote != 0 {
+				if c == '\\' && quote == '"' {
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			}
+			if c == '"' || c == '\'' {
+				quote = c
+				continue
+			}
+			if c != ',' {
+				continue
+			}
+		}
+		item, err := unquoteYAML(num, strings.TrimSpace(inner[start:i]))
+		if err != nil {
+			return nil, err
+		}
+		node.list = append(node.list, yamlScalarNode(num, item))
+		start = i + 1
+	}
+	return node, nil
+}
+
+func unquoteYAML(num int, text string) (string, error) {
+	switch {
+	case len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"':
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return "", fmt.Errorf("line %d: invalid double-quoted string %s", num, text)
+		}
+		return s, nil
+	case len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'':
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'"):
+		return "", fmt.Errorf("line %d: unterminated quoted string", num)
+	}
+	return text, nil
+}
+
+func yamlScalarNode(num int, s string) *yamlNode {
+	return &yamlNode{line: num, scalar: &s}
+}
+
+func (n *yamlNode) yamlString() (string, error) {
+	if n.scalar == nil {
+		return "", fmt.Errorf("line %d: expected a single value", n.line)
+	}
+	return *n.scalar, nil
+}
+
+func (n *yamlNode) yamlInt() (int, error) {
+	s, err := n.yamlString()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: expected a number, got %q", n.line, s)
+	}
+	return v, nil
+}
+
+func (n *yamlNode) yamlBool() (bool, error) {
+	s, err := n.yamlString()
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("line %d: expected true or false, got %q", n.line, s)
+}
+
+// yamlStrings reads a list of scalars; a single scalar is a one-item list.
+func (n *yamlNode) yamlStrings() ([]string, error) {
+	if n.scalar != nil {
+		if *n.scalar == "" {
+			return []string{}, nil
+		}
+		return []string{*n.scalar}, nil
+	}
+	if n.list == nil {
+		return nil, fmt.Errorf("line %d: expected a list", n.line)
+	}
+	values := []string{}
+	for _, item := range n.list {
+		s, err := item.yamlString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
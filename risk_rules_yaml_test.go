@@ -0,0 +1,190 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string { return &s }
+func intPtr(n int) *int       { return &n }
+func boolPtr(b bool) *bool    { return &b }
+
+func TestParseRiskRulesYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want riskRulesYAML
+	}{
+		{
+			name: "readme example",
+			in: `rules:
+  - id: billing-core
+    reason: Touches billing core
+    score: 50
+    paths: [billing-core/]
+  - id: go-todo
+    reason: Adds a TODO
+    score: 5
+    languages: [go]
+    added: ['TODO|FIXME']
+  - id: config          # Reuse a built-in id to change only some fields
+    score: 5
+  - id: termination     # Or to turn it off
+    disabled: true
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{
+				{ID: "billing-core", Reason: strPtr("Touches billing core"), Score: intPtr(50), Paths: []string{"billing-core/"}},
+				{ID: "go-todo", Reason: strPtr("Adds a TODO"), Score: intPtr(5), Languages: []string{"go"}, Added: []string{"TODO|FIXME"}},
+				{ID: "config", Score: intPtr(5)},
+				{ID: "termination", Disabled: true},
+			}},
+		},
+		{
+			name: "flow list with quoted commas",
+			in: `rules:
+  - id: q
+    reason: r
+    added: ['a, b', "c,d", e, 'it''s']
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{
+				{ID: "q", Reason: strPtr("r"), Added: []string{"a, b", "c,d", "e", "it's"}},
+			}},
+		},
+		{
+			name: "empty flow list",
+			in: `rules:
+  - id: q
+    paths: []
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{{ID: "q", Paths: []string{}}}},
+		},
+		{
+			name: "block list items",
+			in: `rules:
+  -
+    id: a
+    reason: R
+    words:
+      - ci
+      - "deploy*"
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{
+				{ID: "a", Reason: strPtr("R"), Words: []string{"ci", "deploy*"}},
+			}},
+		},
+		{
+			name: "lists at the same indent as their key",
+			in: `rules:
+- id: a
+  reason: R
+  paths:
+  - x/
+  - y/
+- id: b
+  disabled: yes
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{
+				{ID: "a", Reason: strPtr("R"), Paths: []string{"x/", "y/"}},
+				{ID: "b", Disabled: true},
+			}},
+		},
+		{
+			name: "comments after quotes",
+			in: `# leading comment
+rules:
+  - id: hash   # trailing
+    reason: "Has # hash" # comment
+    changed: ['a#b', "c # d"] # comment
+    removed: x#y
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{
+				{ID: "hash", Reason: strPtr("Has # hash"), Changed: []string{"a#b", "c # d"}, Removed: []string{"x#y"}},
+			}},
+		},
+		{
+			name: "double-quoted escapes and single-quoted backslashes",
+			in: `rules:
+  - id: esc
+    added: ["\\bfoo\\b", '\bbar\b']
+`,
+			want: riskRulesYAML{Rules: []riskRuleYAML{
+				{ID: "esc", Added: []string{`\bfoo\b`, `\bbar\b`}},
+			}},
+		},
+		{
+			name: "builtin false",
+			in: `---
+builtin: false
+rules:
+`,
+			want: riskRulesYAML{Builtin: boolPtr(false)},
+		},
+		{
+			name: "secrets allow list",
+			in: `secrets:
+  allow:
+    - paths: [testdata/**]
+    - paths: ["*_test.go"]
+      kinds: [jwt]
+    - match: 'sk_test_'
+`,
+			want: riskRulesYAML{SecretAllow: []SecretAllow{
+				{Paths: []string{"testdata/**"}},
+				{Paths: []string{"*_test.go"}, Kinds: []string{"jwt"}},
+				{Match: "sk_test_"},
+			}},
+		},
+		{
+			name: "empty document",
+			in:   "# nothing here\n\n",
+			want: riskRulesYAML{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRiskRulesYAML(tt.in)
+			if err != nil {
+				t.Fatalf("parseRiskRulesYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRiskRulesYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"tab indentation", "rules:\n\t- id: a\n", "line 2: tabs are not allowed"},
+		{"unknown top-level field", "rule:\n  - id: a\n", `line 1: unknown field "rule"`},
+		{"unknown rule field", "rules:\n  - id: a\n    path: [x]\n", `line 3: unknown field "path"`},
+		{"rules not a list", "rules: x\n", "line 1: rules must be a list"},
+		{"rule not a mapping", "rules:\n  - a\n", "line 2: each rule must be a mapping"},
+		{"score not a number", "rules:\n  - id: a\n    score: high\n", `line 3: expected a number, got "high"`},
+		{"builtin not a bool", "builtin: maybe\n", `line 1: expected true or false, got "maybe"`},
+		{"unterminated quote", "rules:\n  - id: 'a\n", "line 2: unterminated quoted string"},
+		{"unterminated flow list", "rules:\n  - id: a\n    paths: [a, b\n", "line 3: unterminated flow list"},
+		{"duplicate field", "rules:\n  - id: a\n    id: b\n", `line 3: duplicate field "id"`},
+		{"bad indentation", "rules:\n  - id: a\n      reason: r\n", "line 3: unexpected indentation"},
+		{"top level list", "- id: a\n", "expected a mapping"},
+		{"unknown secrets field", "secrets:\n  deny: []\n", `line 2: unknown field "deny"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRiskRulesYAML(tt.in)
+			if err == nil {
+				t.Fatalf("expected an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
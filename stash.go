@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StashEntry describes one `git stash list` entry.
+type StashEntry struct {
+	Index        int    `json:"index"`
+	Ref          string `json:"ref"` // stash@{n}; shifts as stashes are pushed and dropped
+	SHA          string `json:"sha"`
+	Message      string `json:"message"`
+	Branch       string `json:"branch"`
+	Date         string `json:"date"`         // ISO 8601
+	HasUntracked bool   `json:"hasUntracked"` // Stashed with --include-untracked
+}
+
+// ListStashes lists the stash entries, newest first.
+func ListStashes(repoPath string) ([]StashEntry, error) {
+	out, err := runGit(repoPath, "stash", "list", "--format=%gd%x1f%H%x1f%gs%x1f%cI%x1f%P")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stashes: %w", err)
+	}
+
+	stashes := []StashEntry{}
+	for _, line := range splitGitLines(out) {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) < 5 {
+			continue
+		}
+
+		entry := StashEntry{
+			Ref:          fields[0],
+			SHA:          fields[1],
+			Message:      fields[2],
+			Date:         fields[3],
+			HasUntracked: len(strings.Fields(fields[4])) >= 3,
+		}
+		if n, ok := strings.CutPrefix(entry.Ref, "stash@{"); ok {
+			entry.Index, _ = strconv.Atoi(strings.TrimSuffix(n, "}"))
+		}
+		// "WIP on main: abc123 subject" or "On main: message"
+		if rest, ok := strings.CutPrefix(entry.Message, "WIP on "); ok {
+			entry.Branch, _, _ = strings.Cut(rest, ":")
+		} else if rest, ok := strings.CutPrefix(entry.Message, "On "); ok {
+			entry.Branch, _, _ = strings.Cut(rest, ":")
+		}
+		stashes = append(stashes, entry)
+	}
+
+	return stashes, nil
+}
+
+// resolveStash returns the commit of a stash ref, checking it is a stash entry.
+func resolveStash(repoPath string, ref string) (string, error) {
+	sha, err := resolveCommit(repoPath, ref)
+	if err != nil {
+		return "", err
+	}
+	stashes, err := ListStashes(repoPath)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range stashes {
+		if s.SHA == sha {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a stash entry", ref)
+}
+
+// stashComparison diffs a stash against the commit it was made on. Untracked
+// files live in a separate parent commit, so they are overlaid on the stash
+// tree in a scratch index to show up as added files.
+func stashComparison(repoPath string, sha string) (diffComparison, error) {
+	base, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", sha+"^1")
+	if err != nil {
+		return diffComparison{}, fmt.Errorf("failed to resolve parent of stash %s: %w", sha, err)
+	}
+	base = strings.TrimSpace(base)
+
+	tree, err := buildScratchTree(repoPath, "stash "+sha, func(env []string) error {
+		if _, err := gitStdout(repoPath, env, "", "read-tree", sha); err != nil {
+			return err
+		}
+		if _, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", sha+"^3"); err != nil {
+			return nil // Stashed without untracked files
+		}
+		untracked, err := gitStdout(repoPath, nil, "", "ls-tree", "-r", "-z", sha+"^3")
+		if err != nil {
+			return err
+		}
+		if untracked == "" {
+			return nil
+		}
+		_, err = gitStdout(repoPath, env, untracked, "update-index", "-z", "--index-info")
+		return err
+	})
+	if err != nil {
+		return diffComparison{}, fmt.Errorf("failed to build stash tree: %w", err)
+	}
+
+	return diffComparison{
+		Args:      []string{base, tree},
+		Old:       fileSource{Kind: "ref", Ref: base},
+		New:       fileSource{Kind: "ref", Ref: tree},
+		BaseLabel: "stash parent",
+		HeadLabel: "stash",
+		BaseSHA:   base,
+		HeadSHA:   sha,
+	}, nil
+}